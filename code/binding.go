@@ -17,15 +17,32 @@ package code
 import (
 	"fmt"
 	"io"
+	"path"
+	"strconv"
 )
 
 type Binding struct {
 	pkg string
-	fps []*Failpoint
+	// pkgPath is the import path of the package; failpoints are registered
+	// under their bare name if it is empty
+	pkgPath string
+	// file is the name of the source file declaring the failpoints, if known
+	file string
+	fps  []*Failpoint
 }
 
 func NewBinding(pkg string, fps []*Failpoint) *Binding {
-	return &Binding{pkg, fps}
+	return &Binding{pkg: pkg, fps: fps}
+}
+
+// NewPackageBinding is like NewBinding, but registers the failpoints under
+// names qualified by the import path of their package, such as
+// "go.etcd.io/etcd/server/wal.Sync", so they don't collide with the
+// failpoints of other packages. Their site is given by the name of the
+// source file declaring them, such as "wal.go", and the line of their gofail
+// comment.
+func NewPackageBinding(pkg, pkgPath, file string, fps []*Failpoint) *Binding {
+	return &Binding{pkg: pkg, pkgPath: pkgPath, file: file, fps: fps}
 }
 
 // Write writes the fp.fail.go file for a package.
@@ -37,11 +54,23 @@ func (b *Binding) Write(dst io.Writer) error {
 		return err
 	}
 	for _, fp := range b.fps {
+		name := fp.Name()
+		if len(b.pkgPath) > 0 {
+			name = b.pkgPath + "." + name
+		}
+		// the site of the gofail comment, rather than of this file,
+		// e.g. "go.etcd.io/etcd/server/wal/wal.go:42"
+		site := ""
+		if len(b.file) > 0 && fp.line > 0 {
+			site = path.Join(b.pkgPath, b.file) + ":" + strconv.Itoa(fp.line)
+		}
 		_, err := fmt.Fprintf(
 			dst,
-			"var %s *runtime.Failpoint = runtime.NewFailpoint(%q)\n",
+			"var %s *runtime.Failpoint = runtime.NewTypedFailpoint(%q, %q, %q)\n",
 			fp.Runtime(),
-			fp.Name(),
+			name,
+			fp.varType,
+			site,
 		)
 		if err != nil {
 			return err
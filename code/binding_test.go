@@ -16,6 +16,8 @@ package code
 
 import (
 	"bytes"
+	"io"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -24,7 +26,7 @@ import (
 func TestBindingWrite(t *testing.T) {
 	pkg := "testing"
 	comment := "// gofail: var Test int\n"
-	expected := "// GENERATED BY GOFAIL. DO NOT EDIT.\n\npackage testing\n\nimport \"go.etcd.io/gofail/runtime\"\n\nvar __fp_Test *runtime.Failpoint = runtime.NewFailpoint(\"Test\")\n"
+	expected := "// GENERATED BY GOFAIL. DO NOT EDIT.\n\npackage testing\n\nimport \"go.etcd.io/gofail/runtime\"\n\nvar __fp_Test *runtime.Failpoint = runtime.NewTypedFailpoint(\"Test\", \"int\", \"\")\n"
 
 	fp, err := newFailpoint(comment)
 	assert.Nilf(t, err, "failed to create failpoint from comment: %s", comment)
@@ -38,3 +40,20 @@ func TestBindingWrite(t *testing.T) {
 	got := buf.String()
 	assert.Equal(t, expected, got)
 }
+
+func TestPackageBindingWrite(t *testing.T) {
+	src := "package wal\n\nfunc Sync() {\n\t// gofail: var Test int\n}\n"
+	expected := "// GENERATED BY GOFAIL. DO NOT EDIT.\n\npackage wal\n\nimport \"go.etcd.io/gofail/runtime\"\n\nvar __fp_Test *runtime.Failpoint = runtime.NewTypedFailpoint(\"go.etcd.io/etcd/server/wal.Test\", \"int\", \"go.etcd.io/etcd/server/wal/wal.go:4\")\n"
+
+	fps, err := ToFailpoints(io.Discard, strings.NewReader(src))
+	assert.Nilf(t, err, "failed to create failpoint from source: %s", src)
+
+	b := NewPackageBinding("wal", "go.etcd.io/etcd/server/wal", "wal.go", fps)
+
+	var buf bytes.Buffer
+	err = b.Write(&buf)
+	assert.Nil(t, err)
+
+	got := buf.String()
+	assert.Equal(t, expected, got)
+}
@@ -23,7 +23,12 @@ import (
 type Failpoint struct {
 	name    string
 	varType string
-	code    []string
+	// ctx is the expression giving the context passed to AcquireContext, set
+	// with a "ctx=<expr>" annotation; Acquire is used if it is empty
+	ctx  string
+	code []string
+	// line is the line of the gofail comment in its file, if known
+	line int
 
 	// whitespace for padding
 	ws string
@@ -41,10 +46,19 @@ func newFailpoint(l string) (*Failpoint, error) {
 	pfx := pfxGofail
 	cmd := strings.SplitAfter(l, pfx)[1]
 	fields := strings.Fields(cmd)
-	if len(fields) != 3 || fields[0] != "var" {
+	if len(fields) < 3 || len(fields) > 4 || fields[0] != "var" {
 		return nil, fmt.Errorf("failpoint: malformed comment header %q", l)
 	}
-	return &Failpoint{name: fields[1], varType: fields[2], ws: strings.Split(l, "//")[0]}, nil
+	fp := &Failpoint{name: fields[1], varType: fields[2], ws: strings.Split(l, "//")[0]}
+	if len(fields) == 4 {
+		// gofail: var <name> <type> ctx=<expr>
+		ctx, ok := strings.CutPrefix(fields[3], "ctx=")
+		if !ok || len(ctx) == 0 {
+			return nil, fmt.Errorf("failpoint: malformed comment header %q", l)
+		}
+		fp.ctx = ctx
+	}
+	return fp, nil
 }
 
 // flush writes the failpoint code to a buffer
@@ -58,7 +72,11 @@ func (fp *Failpoint) flush(dst io.Writer) error {
 func (fp *Failpoint) hdr(varname string) string {
 	ev := errVarGoFail
 
-	hdr := fp.ws + "if v" + fp.name + fmt.Sprintf(", %s := ", ev) + fp.Runtime() + ".Acquire();" + fmt.Sprintf(" %s == nil { ", ev)
+	acquire := ".Acquire()"
+	if len(fp.ctx) > 0 {
+		acquire = ".AcquireContext(" + fp.ctx + ")"
+	}
+	hdr := fp.ws + "if v" + fp.name + fmt.Sprintf(", %s := ", ev) + fp.Runtime() + acquire + ";" + fmt.Sprintf(" %s == nil { ", ev)
 
 	if fp.varType == "struct{}" {
 		// unused
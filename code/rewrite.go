@@ -46,8 +46,10 @@ func ToFailpoints(wdst io.Writer, rsrc io.Reader) ([]*Failpoint, error) {
 	}()
 
 	src := bufio.NewReader(rsrc)
+	line := 0
 	for err == nil {
 		l, rerr := src.ReadString('\n')
+		line++
 		if curfp != nil {
 			if strings.HasPrefix(strings.TrimSpace(l), "//") {
 				if len(l) > 0 && l[len(l)-1] == '\n' {
@@ -66,6 +68,7 @@ func ToFailpoints(wdst io.Writer, rsrc io.Reader) ([]*Failpoint, error) {
 			return nil, err
 		} else if curfp != nil {
 			// found a new failpoint
+			curfp.line = line
 			continue
 		}
 		if _, err = dst.WriteString(l); err != nil {
@@ -112,13 +115,20 @@ func ToComments(wdst io.Writer, rsrc io.Reader) ([]*Failpoint, error) {
 
 			ws = strings.Split(l, "i")[0]
 			n := strings.Split(strings.Split(l, "__fp_")[1], ".")[0]
-			t := strings.Split(strings.Split(l, ".(")[1], ")")[0]
-			dst.WriteString(ws + pfx + " var " + n + " " + t + "\n")
+			acquire, body, _ := strings.Cut(l, fmt.Sprintf("; %s == nil {", errVarGoFail))
+			t := strings.Split(strings.Split(body, ".(")[1], ")")[0]
+			fp := &Failpoint{name: n, varType: t}
+			hdr := ws + pfx + " var " + n + " " + t
+			if _, ctx, ok := strings.Cut(acquire, ".AcquireContext("); ok {
+				fp.ctx = strings.TrimSuffix(ctx, ")")
+				hdr += " ctx=" + fp.ctx
+			}
+			dst.WriteString(hdr + "\n")
 			if !strings.Contains(l, "; goto __nomock") {
 				// not single liner
 				unmatchedBraces = 1
 			}
-			fps = append(fps, &Failpoint{name: n, varType: t})
+			fps = append(fps, fp)
 			continue
 		}
 
@@ -59,6 +59,16 @@ var examples = []struct {
 		"func f() {\n\tif vTest, __fpErr := __fp_Test.Acquire(); __fpErr == nil { Test, __fpTypeOK := vTest.(int); if !__fpTypeOK { goto __badTypeTest} \n\t\t fmt.Println(Test); goto __nomockTest; __badTypeTest: __fp_Test.BadType(vTest, \"int\"); __nomockTest: };\n\n\tif vTest2, __fpErr := __fp_Test2.Acquire(); __fpErr == nil { Test2, __fpTypeOK := vTest2.(int); if !__fpTypeOK { goto __badTypeTest2} \n\t\t fmt.Println(Test2); goto __nomockTest2; __badTypeTest2: __fp_Test2.BadType(vTest2, \"int\"); __nomockTest2: };\n}\n",
 		2,
 	},
+	{
+		"func f() error {\n\t// gofail: var ErrTest error\n\t// return ErrTest\n\treturn nil\n}\n",
+		"func f() error {\n\tif vErrTest, __fpErr := __fp_ErrTest.Acquire(); __fpErr == nil { ErrTest, __fpTypeOK := vErrTest.(error); if !__fpTypeOK { goto __badTypeErrTest} \n\t\t return ErrTest; goto __nomockErrTest; __badTypeErrTest: __fp_ErrTest.BadType(vErrTest, \"error\"); __nomockErrTest: };\n\treturn nil\n}\n",
+		1,
+	},
+	{
+		"func f(r *http.Request) {\n\t// gofail: var CtxTest int ctx=r.Context()\n\t// fmt.Println(CtxTest)\n}\n",
+		"func f(r *http.Request) {\n\tif vCtxTest, __fpErr := __fp_CtxTest.AcquireContext(r.Context()); __fpErr == nil { CtxTest, __fpTypeOK := vCtxTest.(int); if !__fpTypeOK { goto __badTypeCtxTest} \n\t\t fmt.Println(CtxTest); goto __nomockCtxTest; __badTypeCtxTest: __fp_CtxTest.BadType(vCtxTest, \"int\"); __nomockCtxTest: };\n}\n",
+		1,
+	},
 	{
 		"func f() {\n\t// gofail: var NoTypeTest struct{}\n\t// fmt.Println(`hi`)\n}\n",
 		"func f() {\n\tif vNoTypeTest, __fpErr := __fp_NoTypeTest.Acquire(); __fpErr == nil { _, __fpTypeOK := vNoTypeTest.(struct{}); if !__fpTypeOK { goto __badTypeNoTypeTest} \n\t\t fmt.Println(`hi`); goto __nomockNoTypeTest; __badTypeNoTypeTest: __fp_NoTypeTest.BadType(vNoTypeTest, \"struct{}\"); __nomockNoTypeTest: };\n}\n",
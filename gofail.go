@@ -146,10 +146,44 @@ func writeBinding(file string, fps []*code.Failpoint) {
 	// XXX: support "package main"
 	pkgAbsDir := path.Dir(file)
 	pkg := path.Base(pkgAbsDir)
-	code.NewBinding(pkg, fps).Write(out)
+	code.NewPackageBinding(pkg, importPath(pkgAbsDir), path.Base(file), fps).Write(out)
 	out.Close()
 }
 
+// importPath gives the import path of the package in dir from the closest
+// go.mod file, or "" if there is none, in which case failpoints are
+// registered under their bare name.
+func importPath(dir string) string {
+	for modDir := dir; ; modDir = filepath.Dir(modDir) {
+		if mod := modulePath(filepath.Join(modDir, "go.mod")); len(mod) > 0 {
+			rel, err := filepath.Rel(modDir, dir)
+			if err != nil {
+				return ""
+			}
+			return path.Join(mod, filepath.ToSlash(rel))
+		}
+		if filepath.Dir(modDir) == modDir {
+			return ""
+		}
+	}
+}
+
+// modulePath reads the module path declared in a go.mod file.
+func modulePath(gomod string) string {
+	data, err := os.ReadFile(gomod)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line, _, _ = strings.Cut(line, "//")
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], "\"`")
+		}
+	}
+	return ""
+}
+
 func main() {
 	if len(os.Args) < 2 {
 		fmt.Println(usageLine)
@@ -0,0 +1,59 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportPath(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, data string) {
+		p := filepath.Join(root, rel)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(data), 0o644))
+	}
+	write("mod/go.mod", "// the etcd server\nmodule go.etcd.io/etcd/server/v3 // v3\n\ngo 1.23\n")
+	write("mod/storage/wal/wal.go", "package wal\n")
+	write("quoted/go.mod", "module \"example.com/quoted\"\n")
+	write("nested/go.mod", "module example.com/outer\n")
+	write("nested/inner/go.mod", "module example.com/inner\n")
+	write("nested/inner/pkg/pkg.go", "package pkg\n")
+	write("nomod/pkg/pkg.go", "package pkg\n")
+	write("bad/go.mod", "go 1.23\n")
+	write("bad/pkg/pkg.go", "package pkg\n")
+
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{"mod/storage/wal", "go.etcd.io/etcd/server/v3/storage/wal"},
+		{"mod", "go.etcd.io/etcd/server/v3"},
+		{"quoted", "example.com/quoted"},
+		// the closest go.mod wins
+		{"nested/inner/pkg", "example.com/inner/pkg"},
+		{"nomod/pkg", ""},
+		// a go.mod without a module line is skipped
+		{"bad/pkg", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, importPath(filepath.Join(root, tt.dir)), tt.dir)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gofailtest helps unit tests to use failpoints: failpoints are
+// enabled for the duration of a test, and their hits can be asserted.
+//
+//	func TestSomething(t *testing.T) {
+//		gofailtest.Guard(t)
+//		gofailtest.Enable(t, "SomeFuncString", `return("hello")`)
+//		...
+//		gofailtest.AssertHits(t, "SomeFuncString", 1)
+//	}
+package gofailtest
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"go.etcd.io/gofail/runtime"
+)
+
+// Enable sets a failpoint to the given terms until the end of the test, when
+// the failpoint gets back the terms it had before, or is disabled. The test
+// fails immediately if the failpoint doesn't exist or the terms are invalid.
+func Enable(t testing.TB, name, terms string) {
+	t.Helper()
+	prev, _, err := runtime.Status(name)
+	if err != nil && !errors.Is(err, runtime.ErrDisabled) {
+		t.Fatalf("failed to enable failpoint %s: %v", name, err)
+		return
+	}
+	wasEnabled := err == nil
+	if err := runtime.Enable(name, terms); err != nil {
+		t.Fatalf("failed to enable failpoint %s=%s: %v", name, terms, err)
+		return
+	}
+	t.Cleanup(func() {
+		if wasEnabled {
+			if err := runtime.Enable(name, prev); err != nil {
+				t.Errorf("failed to restore failpoint %s=%s: %v", name, prev, err)
+			}
+			return
+		}
+		if err := runtime.Disable(name); err != nil && !errors.Is(err, runtime.ErrDisabled) {
+			t.Errorf("failed to disable failpoint %s: %v", name, err)
+		}
+	})
+}
+
+// EnableFor sets a failpoint to the given terms for the code running under
+// the returned context, see runtime.EnableFor, until the end of the test. It
+// lets parallel tests use the same failpoint.
+func EnableFor(t testing.TB, ctx context.Context, name, terms string) context.Context {
+	t.Helper()
+	ctx, err := runtime.EnableFor(ctx, name, terms)
+	if err != nil {
+		t.Fatalf("failed to enable failpoint %s=%s: %v", name, terms, err)
+		return ctx
+	}
+	t.Cleanup(func() { runtime.DisableFor(ctx, name) })
+	return ctx
+}
+
+// Hits gives the number of times a failpoint executed one of its terms since
+// it was enabled, or 0 if it is disabled. The test fails immediately if the
+// failpoint doesn't exist.
+func Hits(t testing.TB, name string) int {
+	t.Helper()
+	_, count, err := runtime.Status(name)
+	if err != nil && !errors.Is(err, runtime.ErrDisabled) {
+		t.Fatalf("failed to get the hits of failpoint %s: %v", name, err)
+	}
+	return count
+}
+
+// AssertHits checks that a failpoint executed one of its terms n times since
+// it was enabled, and marks the test as failed otherwise. It returns whether
+// the assertion holds.
+func AssertHits(t testing.TB, name string, n int) bool {
+	t.Helper()
+	if hits := Hits(t, name); hits != n {
+		t.Errorf("failpoint %s: got %d hits, expected %d", name, hits, n)
+		return false
+	}
+	return true
+}
+
+// RequireNotHit checks that a failpoint didn't execute any of its terms since
+// it was enabled, and fails the test immediately otherwise.
+func RequireNotHit(t testing.TB, name string) {
+	t.Helper()
+	if hits := Hits(t, name); hits != 0 {
+		t.Fatalf("failpoint %s: got %d hits, expected none", name, hits)
+	}
+}
+
+// Guard fails the test if, when it ends, failpoints are left enabled with
+// terms they didn't have when Guard was called. Cleanups run in the reverse
+// order they were registered, so Guard must be called before Enable to check
+// what remains after the failpoints enabled by the test are restored.
+func Guard(t testing.TB) {
+	t.Helper()
+	before := enabled()
+	t.Cleanup(func() {
+		var leaked []string
+		for name, terms := range enabled() {
+			if prev, ok := before[name]; !ok || prev != terms {
+				leaked = append(leaked, name+"="+terms)
+			}
+		}
+		if len(leaked) > 0 {
+			sort.Strings(leaked)
+			t.Errorf("failpoints left enabled: %v", leaked)
+		}
+	})
+}
+
+// enabled gives the terms of the enabled failpoints by name.
+func enabled() map[string]string {
+	fps := make(map[string]string)
+	for _, name := range runtime.List() {
+		if terms, _, err := runtime.Status(name); err == nil {
+			fps[name] = terms
+		}
+	}
+	return fps
+}
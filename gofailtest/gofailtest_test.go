@@ -0,0 +1,142 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gofailtest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.etcd.io/gofail/runtime"
+)
+
+var (
+	fpA = runtime.NewFailpoint("go.etcd.io/gofail/gofailtest.A")
+	fpB = runtime.NewFailpoint("go.etcd.io/gofail/gofailtest.B")
+)
+
+// fakeT records the failures of a test instead of failing it.
+type fakeT struct {
+	testing.TB
+	errors   []string
+	fatal    bool
+	cleanups []func()
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Fatalf(format string, args ...interface{}) {
+	f.Errorf(format, args...)
+	f.fatal = true
+}
+
+func (f *fakeT) Cleanup(fn func()) { f.cleanups = append(f.cleanups, fn) }
+
+func (f *fakeT) end() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func TestEnable(t *testing.T) {
+	ft := &fakeT{}
+	Guard(ft)
+	Enable(ft, "A", "return(1)")
+	Enable(ft, "B", "2*return(2)")
+	fpA.Acquire()
+	fpB.Acquire()
+	fpB.Acquire()
+	assert.True(t, AssertHits(ft, "A", 1))
+	assert.True(t, AssertHits(ft, "B", 2))
+	ft.end()
+	assert.Empty(t, ft.errors)
+
+	for _, name := range []string{"A", "B"} {
+		_, _, err := runtime.Status(name)
+		assert.ErrorIs(t, err, runtime.ErrDisabled, name)
+	}
+}
+
+func TestEnableRestoresPreviousTerms(t *testing.T) {
+	require.NoError(t, runtime.Enable("A", "return(1)"))
+	defer runtime.Disable("A")
+
+	ft := &fakeT{}
+	Enable(ft, "A", "return(2)")
+	v, _ := fpA.Acquire()
+	assert.Equal(t, 2, v)
+	ft.end()
+
+	v, _ = fpA.Acquire()
+	assert.Equal(t, 1, v)
+}
+
+func TestEnableFailures(t *testing.T) {
+	ft := &fakeT{}
+	Enable(ft, "Unknown", "return(1)")
+	assert.True(t, ft.fatal)
+
+	ft = &fakeT{}
+	Enable(ft, "A", "retrun(1)")
+	assert.True(t, ft.fatal)
+	assert.Empty(t, ft.cleanups)
+}
+
+func TestEnableFor(t *testing.T) {
+	ft := &fakeT{}
+	ctx := EnableFor(ft, context.Background(), "A", "return(1)")
+	v, err := fpA.AcquireContext(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+	ft.end()
+
+	_, err = fpA.AcquireContext(ctx)
+	assert.ErrorIs(t, err, runtime.ErrDisabled)
+}
+
+func TestHitAssertions(t *testing.T) {
+	ft := &fakeT{}
+	Enable(ft, "A", "off")
+	RequireNotHit(ft, "A")
+	RequireNotHit(ft, "B")
+	assert.Empty(t, ft.errors)
+
+	fpA.Acquire()
+	assert.False(t, AssertHits(ft, "A", 2))
+	assert.False(t, ft.fatal)
+	RequireNotHit(ft, "A")
+	assert.True(t, ft.fatal)
+	assert.Equal(t, []string{
+		"failpoint A: got 1 hits, expected 2",
+		"failpoint A: got 1 hits, expected none",
+	}, ft.errors)
+	ft.end()
+}
+
+func TestGuard(t *testing.T) {
+	ft := &fakeT{}
+	Guard(ft)
+	require.NoError(t, runtime.Enable("A", "return(1)"))
+	defer runtime.Disable("A")
+	ft.end()
+	assert.Equal(t, []string{"failpoints left enabled: [go.etcd.io/gofail/gofailtest.A=return(1)]"}, ft.errors)
+}
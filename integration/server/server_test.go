@@ -222,13 +222,25 @@ func sendRequest(t *testing.T, port int, method string, endpoint string, data []
 
 // request generation helpers
 
+// failpointsPkg is the import path of the package declaring the failpoints,
+// which qualifies their names in the listing.
+const failpointsPkg = "go.etcd.io/gofail/integration/server/failpoints"
+
+// rgListAllSuccess expects the listing of the failpoints given by their short
+// names.
 func rgListAllSuccess(expected string) testRequest {
+	lines := strings.Split(expected, "\n")
+	for i := range lines {
+		if len(lines[i]) > 0 {
+			lines[i] = failpointsPkg + "." + lines[i]
+		}
+	}
 	return &gofailTestRequest{
 		requestType: "listall",
 		request: request{
 			expected: response{
 				statusCode: 200,
-				body:       expected,
+				body:       strings.Join(lines, "\n"),
 			},
 		},
 	}
@@ -603,7 +615,7 @@ func TestAll(t *testing.T) {
 			},
 		},
 		{
-			name: "failpoints ignores invalid failpoints, and processes valid ones",
+			name: "failpoints rejects all failpoints if any is invalid",
 			requests: []testRequest{
 				&gofailTestRequest{
 					requestType: "failpoints",
@@ -611,16 +623,18 @@ func TestAll(t *testing.T) {
 						args: []string{
 							"ExampleOneLine=return(\"jkl\")",
 							"InvalidFailpoint=return",
+							"OtherInvalidFailpoint=return",
 						},
 						expected: response{
 							statusCode: 400,
-							body:       "fail to set failpoint: failpoint: failpoint does not exist\n",
+							body: "fail to set failpoint: InvalidFailpoint: failpoint: failpoint does not exist\n" +
+								"OtherInvalidFailpoint: failpoint: failpoint does not exist\n",
 						},
 					},
 				},
 				rgListAllSuccess(strings.Join([]string{
 					"ExampleString=1*return(\"fail string1\")->return(\"fail string2\")",
-					"ExampleOneLine=return(\"jkl\")",
+					"ExampleOneLine=return(\"ghi\")",
 					"ExampleLabels=return"}, "\n") + "\n"),
 				rgCountSuccess("ExampleString", 2),
 				rgCountSuccess("ExampleOneLine", 0),
@@ -670,7 +684,15 @@ func TestAll(t *testing.T) {
 						},
 						expected: response{
 							statusCode: 400,
-							body:       "fail to set failpoint: failpoint: could not parse terms\n",
+							body: "fail to parse failpoint: failpoint: could not parse " +
+								"\"ExampleString=;ExampleOneLine=;ExampleLabels=\" at offset 14: " +
+								"expected an action (break, error, off, panic, pause, print, return, sleep), found \";\"\n" +
+								"failpoint: could not parse " +
+								"\"ExampleString=;ExampleOneLine=;ExampleLabels=\" at offset 30: " +
+								"expected an action (break, error, off, panic, pause, print, return, sleep), found \";\"\n" +
+								"failpoint: could not parse " +
+								"\"ExampleString=;ExampleOneLine=;ExampleLabels=\" at offset 45: " +
+								"expected an action (break, error, off, panic, pause, print, return, sleep), found end of input\n",
 						},
 					},
 				},
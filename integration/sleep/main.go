@@ -36,9 +36,10 @@ func main() {
 	}
 
 	{
-		// expectation: this part of the code will take about 6s to execute only,
-		// because all go routines will be executing concurrently, with both the sleep
-		// from failpoint and the original sleep actions
+		// expectation: this part of the code will take about 3s to execute only,
+		// because all go routines will be executing concurrently, and the sleep
+		// from failpoint is interrupted as soon as the failpoint is disabled, leaving
+		// only the original sleep actions
 		//
 		// The gofail implementation up till commit 93c579a86c46 is executing the
 		// program sequentially, due to the failpoint action execution and enable/disable
@@ -72,7 +73,7 @@ func main() {
 		wg.Wait()
 
 		elapsed := time.Since(start)
-		if elapsed > (6*time.Second + 100*time.Millisecond) {
+		if elapsed > (3*time.Second + 200*time.Millisecond) {
 			log.Fatalln("invalid execution time", elapsed)
 		}
 
@@ -0,0 +1,102 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// authorize checks the bearer token of a request, and reports an error to
+// the client if the request isn't allowed. Without any token set, every
+// request is. Otherwise, the token of GOFAIL_HTTP_TOKEN allows every request,
+// and the token of GOFAIL_HTTP_READONLY_TOKEN only allows GET requests, which
+// can't change the failpoints.
+func (h *httpHandler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if len(h.token) == 0 && len(h.readOnlyToken) == 0 {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	switch {
+	case ok && matchToken(token, h.token):
+		return true
+	case ok && matchToken(token, h.readOnlyToken):
+		if r.Method == "GET" || r.Method == "HEAD" {
+			return true
+		}
+		authError(w, r, http.StatusForbidden, "forbidden", "the token only allows GET requests")
+	default:
+		w.Header().Set("WWW-Authenticate", `Bearer realm="gofail"`)
+		authError(w, r, http.StatusUnauthorized, "unauthorized", "missing or invalid bearer token")
+	}
+	return false
+}
+
+// matchToken compares a token to the one expected in constant time, so the
+// time taken doesn't tell how much of it is right. An empty token never
+// matches.
+func matchToken(token, expected string) bool {
+	return len(expected) > 0 && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+func authError(w http.ResponseWriter, r *http.Request, status int, code, msg string) {
+	if strings.HasPrefix(r.URL.Path, "/v2/") {
+		writeError(w, status, code, errors.New(msg))
+		return
+	}
+	http.Error(w, msg, status)
+}
+
+// tlsConfig gives the TLS configuration of the HTTP endpoint from the files
+// of its certificate and key, or nil if they aren't set. Clients must present
+// a certificate signed by the certificate authority in caFile, if set.
+func tlsConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	if len(certFile) == 0 && len(keyFile) == 0 {
+		if len(caFile) > 0 {
+			return nil, fmt.Errorf("failpoint: GOFAIL_HTTP_TLS_CA requires GOFAIL_HTTP_TLS_CERT and GOFAIL_HTTP_TLS_KEY")
+		}
+		return nil, nil
+	}
+	if len(certFile) == 0 || len(keyFile) == 0 {
+		return nil, fmt.Errorf("failpoint: GOFAIL_HTTP_TLS_CERT and GOFAIL_HTTP_TLS_KEY must be set together")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failpoint: fail to load TLS certificate: %v", err)
+	}
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if len(caFile) > 0 {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failpoint: fail to load TLS CA: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("failpoint: no certificate found in %s", caFile)
+		}
+		conf.ClientCAs = pool
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return conf, nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorize(t *testing.T) {
+	defer clearGlobalVars()
+
+	NewFailpoint("failpoint")
+	h := &httpHandler{token: "secret", readOnlyToken: "observer"}
+	for _, tt := range []struct {
+		method, path, auth string
+		status             int
+	}{
+		{"GET", "/failpoint", "", http.StatusUnauthorized},
+		{"GET", "/failpoint", "Bearer wrong", http.StatusUnauthorized},
+		{"GET", "/failpoint", "secret", http.StatusUnauthorized},
+		{"PUT", "/failpoint", "Bearer secret", http.StatusNoContent},
+		{"GET", "/failpoint", "Bearer observer", http.StatusOK},
+		{"GET", "/v2/failpoints", "Bearer observer", http.StatusOK},
+		{"PUT", "/failpoint", "Bearer observer", http.StatusForbidden},
+		{"DELETE", "/v2/failpoints/failpoint", "Bearer observer", http.StatusForbidden},
+		{"DELETE", "/v2/failpoints/failpoint", "Bearer secret", http.StatusNoContent},
+	} {
+		r := httptest.NewRequest(tt.method, tt.path, strings.NewReader("return(1)"))
+		if len(tt.auth) > 0 {
+			r.Header.Set("Authorization", tt.auth)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, tt.status, w.Code, "%s %s %s", tt.method, tt.path, tt.auth)
+		if tt.status == http.StatusUnauthorized {
+			assert.Equal(t, `Bearer realm="gofail"`, w.Header().Get("WWW-Authenticate"))
+		}
+	}
+
+	// a read-only token alone doesn't allow any change
+	h = &httpHandler{readOnlyToken: "observer"}
+	r := httptest.NewRequest("PUT", "/failpoint", nil)
+	r.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := newCert(t, nil, nil, dir, "ca")
+	newCert(t, ca, caKey, dir, "server")
+	client, clientKey := newCert(t, ca, caKey, dir, "client")
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	conf, err := tlsConfig("", "", "")
+	assert.NoError(t, err)
+	assert.Nil(t, conf)
+	for _, files := range [][3]string{
+		{path("server.crt"), "", ""},
+		{"", "", path("ca.crt")},
+		{path("server.crt"), path("client.key"), ""},
+		{path("server.crt"), path("server.key"), path("server.key")},
+	} {
+		_, err := tlsConfig(files[0], files[1], files[2])
+		assert.Error(t, err, files)
+	}
+
+	conf, err = tlsConfig(path("server.crt"), path("server.key"), path("ca.crt"))
+	require.NoError(t, err)
+	s := httptest.NewUnstartedServer(&httpHandler{})
+	s.TLS = conf
+	// the handshake without a client certificate is expected to fail
+	s.Config.ErrorLog = log.New(io.Discard, "", 0)
+	s.StartTLS()
+	defer s.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	get := func(certs ...tls.Certificate) error {
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}}
+		resp, err := c.Get(s.URL + "/")
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	assert.Error(t, get())
+	assert.NoError(t, get(tls.Certificate{Certificate: [][]byte{client.Raw}, PrivateKey: clientKey}))
+}
+
+// newCert writes a certificate and its key to <name>.crt and <name>.key in
+// dir, signed by parent or self-signed as a certificate authority.
+func newCert(t *testing.T, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, dir, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+	return cert, key
+}
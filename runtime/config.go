@@ -0,0 +1,280 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	fpterms "go.etcd.io/gofail/terms"
+)
+
+var (
+	ErrBadConfig = fmt.Errorf("failpoint: invalid configuration file")
+
+	// configPath is the file named by GOFAIL_CONFIG
+	configPath string
+	// configMu serializes the loading of the configuration file, and
+	// protects config and configData
+	configMu sync.Mutex
+	// config is the configuration last applied
+	config *Config
+	// configData is the content of the file last read, applied or not, and
+	// nil when it could not be read
+	configData []byte
+)
+
+// Config is the content of the configuration file named by GOFAIL_CONFIG,
+// written in JSON when the file name ends with ".json", and in YAML
+// otherwise:
+//
+//	failpoints:
+//	  raftBeforeSave:
+//	    terms: sleep("100ms")
+//	    comment: slow down the WAL
+//	    owner: chaos
+//	  "*BeforeCommit":
+//	    terms: 1%panic
+type Config struct {
+	// Failpoints are the entries by failpoint name or pattern.
+	Failpoints map[string]ConfigEntry `json:"failpoints" yaml:"failpoints"`
+}
+
+// ConfigEntry sets a failpoint, or the failpoints matching a pattern, to the
+// given terms. The comment and owner are left to the people and tools
+// editing the file.
+type ConfigEntry struct {
+	Terms   string `json:"terms" yaml:"terms"`
+	Comment string `json:"comment,omitempty" yaml:"comment,omitempty"`
+	Owner   string `json:"owner,omitempty" yaml:"owner,omitempty"`
+}
+
+// parseConfig decodes a configuration file and checks the syntax of its
+// entries.
+func parseConfig(path string, data []byte) (*Config, error) {
+	c := &Config{}
+	var err error
+	if filepath.Ext(path) == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(c)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		// an empty file is an empty configuration
+		if err = dec.Decode(c); errors.Is(err, io.EOF) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %v", ErrBadConfig, path, err)
+	}
+
+	names := make([]string, 0, len(c.Failpoints))
+	for name := range c.Failpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var errs []error
+	for _, name := range names {
+		if isPattern(name) {
+			if _, err := newPattern(name, ""); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		if err := fpterms.Validate(c.Failpoints[name].Terms); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w %s:\n%w", ErrBadConfig, path, errors.Join(errs...))
+	}
+	return c, nil
+}
+
+// list gives the entries of the configuration in the order of their names,
+// in which its patterns are set.
+func (c *Config) list() []fpterms.FailpointTerms {
+	if c == nil {
+		return nil
+	}
+	names := make([]string, 0, len(c.Failpoints))
+	for name := range c.Failpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fps := make([]fpterms.FailpointTerms, len(names))
+	for i, name := range names {
+		fps[i] = fpterms.FailpointTerms{Name: name, Terms: c.Failpoints[name].Terms}
+	}
+	return fps
+}
+
+// loadConfig reads the configuration file at startup, before any failpoint
+// is registered. Its entries apply as those of GOFAIL_FAILPOINTS, which take
+// precedence: its patterns are set before those of GOFAIL_FAILPOINTS, so that
+// the latter apply when both match a failpoint.
+func loadConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadConfig, err)
+	}
+	c, err := parseConfig(path, data)
+	if err != nil {
+		return err
+	}
+
+	failpointsMu.Lock()
+	var ps []*pattern
+	for _, fp := range c.list() {
+		if !isPattern(fp.Name) {
+			if _, ok := envTerms[fp.Name]; !ok {
+				envTerms[fp.Name] = fp.Terms
+			}
+			continue
+		}
+		if !hasPattern(fp.Name) {
+			p, _ := newPattern(fp.Name, fp.Terms)
+			ps = append(ps, p)
+		}
+	}
+	patterns = append(ps, patterns...)
+	failpointsMu.Unlock()
+
+	configMu.Lock()
+	defer configMu.Unlock()
+	configPath, config, configData = path, c, data
+	return nil
+}
+
+// hasPattern reports whether a pattern was set; failpointsMu must be held.
+func hasPattern(expr string) bool {
+	for _, p := range patterns {
+		if p.expr == expr {
+			return true
+		}
+	}
+	return false
+}
+
+// reloadConfig reads the configuration file again and, when it changed,
+// applies the changes at once as EnableAll does: the entries that were
+// added or whose terms changed are enabled, and those that were removed are
+// disabled. As with loadConfig, the names of failpoints which aren't
+// registered yet are kept for when they are, and GOFAIL_FAILPOINTS takes
+// precedence. Nothing is applied if the file is invalid, and the file is not
+// tried again until it changes.
+func reloadConfig() (changed bool, err error) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	if configPath == "" {
+		return false, nil
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		// report a file going missing once, rather than at every poll
+		if configData == nil {
+			return false, nil
+		}
+		configData = nil
+		return true, fmt.Errorf("%w: %v", ErrBadConfig, err)
+	}
+	if bytes.Equal(data, configData) {
+		return false, nil
+	}
+	configData = data
+
+	c, err := parseConfig(configPath, data)
+	if err != nil {
+		return true, err
+	}
+	if err := applyConfig(c, false); err != nil {
+		return true, fmt.Errorf("%w %s:\n%w", ErrBadConfig, configPath, err)
+	}
+	return true, nil
+}
+
+// applyConfig applies the configuration c in place of the one applied last,
+// all at once as EnableAll does: the entries added or whose terms changed
+// since are enabled, or every entry with all, and the removed ones are
+// disabled. As at startup, GOFAIL_FAILPOINTS takes precedence, and the entries
+// are kept for the failpoints registered later on; with all, the entries of
+// GOFAIL_FAILPOINTS are applied again too. Nothing changes if an entry is
+// invalid. c may be nil without a configuration file; configMu must be held.
+func applyConfig(c *Config, all bool) error {
+	var old, cur map[string]ConfigEntry
+	if config != nil {
+		old = config.Failpoints
+	}
+	if c != nil {
+		cur = c.Failpoints
+	}
+	// GOFAIL_FAILPOINTS was checked at startup
+	env, _ := parseFailpoints(os.Getenv("GOFAIL_FAILPOINTS"))
+	fromEnv := make(map[string]bool, len(env))
+	for _, fp := range env {
+		fromEnv[fp.Name] = true
+	}
+
+	var fps []fpterms.FailpointTerms
+	for _, fp := range c.list() {
+		if e, ok := old[fp.Name]; (all || !ok || e.Terms != fp.Terms) && !fromEnv[fp.Name] {
+			fps = append(fps, fp)
+		}
+	}
+	if all {
+		// last, to take precedence over the patterns of the file
+		fps = append(fps, env...)
+	}
+	var disable []string
+	for name := range old {
+		if _, ok := cur[name]; !ok && !fromEnv[name] {
+			disable = append(disable, name)
+		}
+	}
+
+	if err := updateEnv(fps, disable); err != nil {
+		return err
+	}
+	config = c
+	return nil
+}
+
+// watchConfig polls the configuration file for changes until Shutdown.
+func watchConfig(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-shutdown:
+			return
+		}
+		if _, err := reloadConfig(); err != nil {
+			fmt.Printf("fail to reload failpoints: %v\n", err)
+		}
+	}
+}
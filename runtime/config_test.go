@@ -0,0 +1,278 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfig(t *testing.T) {
+	want := &Config{Failpoints: map[string]ConfigEntry{
+		"failpoint1":    {Terms: `sleep("100ms")`, Comment: "slow down", Owner: "chaos"},
+		"*BeforeCommit": {Terms: `1%panic`},
+	}}
+
+	c, err := parseConfig("gofail.yaml", []byte(`
+failpoints:
+  failpoint1:
+    terms: sleep("100ms")
+    comment: slow down
+    owner: chaos
+  "*BeforeCommit":
+    terms: 1%panic
+`))
+	require.NoError(t, err)
+	assert.Equal(t, want, c)
+
+	c, err = parseConfig("gofail.json", []byte(`{"failpoints": {
+		"failpoint1": {"terms": "sleep(\"100ms\")", "comment": "slow down", "owner": "chaos"},
+		"*BeforeCommit": {"terms": "1%panic"}
+	}}`))
+	require.NoError(t, err)
+	assert.Equal(t, want, c)
+
+	c, err = parseConfig("gofail.yaml", nil)
+	require.NoError(t, err)
+	assert.Empty(t, c.Failpoints)
+
+	for _, tt := range []struct {
+		path string
+		data string
+	}{
+		{"gofail.yaml", "failpoints:\n  failpoint1:\n    term: off\n"},
+		{"gofail.json", `{"failpoints": {"failpoint1": {"terms": "off"}}, "other": 1}`},
+		{"gofail.yaml", "failpoints:\n  failpoint1:\n    terms: retrun(1)\n"},
+		{"gofail.yaml", "failpoints:\n  failpoint1:\n    terms: \"\"\n"},
+		{"gofail.yaml", "failpoints:\n  \"[\":\n    terms: off\n"},
+	} {
+		_, err := parseConfig(tt.path, []byte(tt.data))
+		assert.ErrorIs(t, err, ErrBadConfig, tt.data)
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	defer clearGlobalVars()
+
+	path := filepath.Join(t.TempDir(), "gofail.yaml")
+	write := func(data string) {
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+	}
+	write(`
+failpoints:
+  failpoint1:
+    terms: return(1)
+  late*:
+    terms: return(2)
+`)
+	require.NoError(t, loadConfig(path))
+	fp1 := NewFailpoint("failpoint1")
+	fp2 := NewFailpoint("failpoint2")
+	fp3 := NewFailpoint("lateFailpoint")
+	assert.Equal(t, 1, mustAcquire(t, fp1))
+	assert.Equal(t, 2, mustAcquire(t, fp3))
+
+	changed, err := reloadConfig()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// the unchanged entries keep their state, the removed ones are disabled
+	require.NoError(t, Enable("failpoint1", `1*return(3)`))
+	write(`
+failpoints:
+  failpoint1:
+    terms: return(1)
+  failpoint2:
+    terms: return(4)
+`)
+	changed, err = reloadConfig()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, 3, mustAcquire(t, fp1))
+	assert.Equal(t, 4, mustAcquire(t, fp2))
+	_, err = fp3.Acquire()
+	assert.ErrorIs(t, err, ErrDisabled)
+
+	// the failpoints which aren't registered yet get their terms when they
+	// are, as at startup
+	write(`
+failpoints:
+  failpoint2:
+    terms: return(5)
+  unknown:
+    terms: return(6)
+`)
+	_, err = reloadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, mustAcquire(t, fp2))
+	_, err = fp1.Acquire()
+	assert.ErrorIs(t, err, ErrDisabled)
+	fp4 := NewFailpoint("unknown")
+	assert.Equal(t, 6, mustAcquire(t, fp4))
+
+	// an invalid file changes nothing, and is reported once
+	write(`
+failpoints:
+  failpoint2:
+    terms: retrun(7)
+`)
+	_, err = reloadConfig()
+	assert.ErrorIs(t, err, ErrBadConfig)
+	assert.ErrorIs(t, err, ErrBadParse)
+	changed, err = reloadConfig()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, 5, mustAcquire(t, fp2))
+
+	// the next valid file applies against the last one applied
+	write(`
+failpoints:
+  failpoint2:
+    terms: return(5)
+`)
+	_, err = reloadConfig()
+	assert.NoError(t, err)
+	_, err = fp4.Acquire()
+	assert.ErrorIs(t, err, ErrDisabled)
+	assert.Equal(t, 5, mustAcquire(t, fp2))
+}
+
+func TestReloadConfigInvalidTerms(t *testing.T) {
+	defer clearGlobalVars()
+
+	path := filepath.Join(t.TempDir(), "gofail.yaml")
+	write := func(data string) {
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+	}
+	write(`
+failpoints:
+  failpoint1:
+    terms: return(1)
+`)
+	require.NoError(t, loadConfig(path))
+	fp1 := NewFailpoint("failpoint1")
+
+	// terms which parse but can't be applied change nothing either, not even
+	// for the failpoints registered later on
+	write(`
+failpoints:
+  failpoint1:
+    terms: return(@Missing)
+  lazy:
+    terms: return(2)
+`)
+	_, err := reloadConfig()
+	assert.ErrorIs(t, err, ErrBadConfig)
+	assert.Equal(t, 1, mustAcquire(t, fp1))
+	_, err = NewFailpoint("lazy").Acquire()
+	assert.ErrorIs(t, err, ErrDisabled)
+	assert.Equal(t, map[string]string{"failpoint1": "return(1)"}, envTerms)
+}
+
+func TestReloadConfigShortName(t *testing.T) {
+	defer clearGlobalVars()
+
+	path := filepath.Join(t.TempDir(), "gofail.yaml")
+	write := func(data string) {
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+	}
+	write("failpoints: {}\n")
+	require.NoError(t, loadConfig(path))
+	fp1 := NewFailpoint("go.etcd.io/a.Sync")
+	fp2 := NewFailpoint("go.etcd.io/b.Sync")
+	fp3 := NewFailpoint("go.etcd.io/c.Sync")
+
+	// a short name applies to the failpoints of every package, but for those
+	// with an entry of their own
+	write(`
+failpoints:
+  Sync:
+    terms: return(1)
+  go.etcd.io/c.Sync:
+    terms: return(2)
+`)
+	_, err := reloadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 1, mustAcquire(t, fp1))
+	assert.Equal(t, 1, mustAcquire(t, fp2))
+	assert.Equal(t, 2, mustAcquire(t, fp3))
+
+	// and so does its removal
+	write(`
+failpoints:
+  go.etcd.io/c.Sync:
+    terms: return(2)
+`)
+	_, err = reloadConfig()
+	require.NoError(t, err)
+	for _, fp := range []*Failpoint{fp1, fp2} {
+		_, err = fp.Acquire()
+		assert.ErrorIs(t, err, ErrDisabled)
+	}
+	assert.Equal(t, 2, mustAcquire(t, fp3))
+
+	// a failpoint whose entry is removed gets the terms of the entries still
+	// applying to it
+	write(`
+failpoints:
+  Sync:
+    terms: return(3)
+  go.etcd.io/c.Sync:
+    terms: return(2)
+`)
+	_, err = reloadConfig()
+	require.NoError(t, err)
+	write(`
+failpoints:
+  Sync:
+    terms: return(3)
+`)
+	_, err = reloadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 3, mustAcquire(t, fp3))
+}
+
+func TestLoadConfigPatterns(t *testing.T) {
+	defer clearGlobalVars()
+
+	path := filepath.Join(t.TempDir(), "gofail.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+failpoints:
+  "*Save":
+    terms: return(1)
+  "raft*":
+    terms: return(2)
+  "*Commit":
+    terms: return(3)
+`), 0o644))
+	require.NoError(t, loadFailpoints("*Commit=return(4)"))
+	require.NoError(t, loadConfig(path))
+
+	// the patterns of the file apply in the order of their names, and those
+	// of GOFAIL_FAILPOINTS take precedence
+	assert.Equal(t, 2, mustAcquire(t, NewFailpoint("raftSave")))
+	assert.Equal(t, 4, mustAcquire(t, NewFailpoint("raftCommit")))
+}
+
+func mustAcquire(t *testing.T, fp *Failpoint) interface{} {
+	t.Helper()
+	v, err := fp.Acquire()
+	require.NoError(t, err)
+	return v
+}
@@ -0,0 +1,126 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"context"
+	"sync"
+)
+
+// scopeKey is the context key of the failpoint terms enabled by EnableFor.
+type scopeKey struct{}
+
+// scope holds the terms of a failpoint enabled for a context, and links to
+// the scope of its parent context, if any.
+type scope struct {
+	fp     *Failpoint
+	parent *scope
+	// done is closed once the context the terms were enabled for is done,
+	// which disables the scope
+	done <-chan struct{}
+
+	// mu protects t, which is nil once the scope is disabled
+	mu sync.RWMutex
+	t  *terms
+}
+
+// EnableFor sets a failpoint to a given failpoint description for the code
+// running under the returned context only, that is the code reaching the
+// failpoint through Failpoint.AcquireContext with that context or a context
+// derived from it. Such code sees these terms instead of those set by Enable,
+// while the rest of the process is unaffected, so that parallel tests can
+// use the same failpoint independently.
+//
+// Once ctx is done, the terms are disabled as by DisableFor, and the
+// goroutines sleeping or paused by them are woken up.
+func EnableFor(ctx context.Context, name, inTerms string) (context.Context, error) {
+	failpointsMu.RLock()
+	fp, err := lookup(name)
+	failpointsMu.RUnlock()
+	if err != nil {
+		return ctx, err
+	}
+
+	t, err := newTerms(fp.name, inTerms)
+	if err != nil {
+		return ctx, err
+	}
+	parent, _ := ctx.Value(scopeKey{}).(*scope)
+	s := &scope{fp: fp, parent: parent, done: ctx.Done(), t: t}
+	context.AfterFunc(ctx, func() { s.clear() })
+	return context.WithValue(ctx, scopeKey{}, s), nil
+}
+
+// DisableFor clears the terms set by the innermost EnableFor of ctx for a
+// failpoint; the code running under ctx sees the terms of the enclosing scope
+// again, or those set by Enable.
+func DisableFor(ctx context.Context, name string) error {
+	failpointsMu.RLock()
+	fp, err := lookup(name)
+	failpointsMu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	for s, _ := ctx.Value(scopeKey{}).(*scope); s != nil; s = s.parent {
+		if s.fp == fp && s.active() && s.clear() {
+			return nil
+		}
+	}
+	return ErrDisabled
+}
+
+// clear disables the scope and stops its terms, and reports whether it was
+// enabled.
+func (s *scope) clear() bool {
+	s.mu.Lock()
+	t := s.t
+	s.t = nil
+	s.mu.Unlock()
+	if t == nil {
+		return false
+	}
+	t.stop()
+	return true
+}
+
+// active reports whether the context of the scope is still running; the
+// scope of a done context no longer applies, even before clear is called for
+// it.
+func (s *scope) active() bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// scopedTerms gives the terms of the innermost scope of ctx enabling fp, or
+// nil if there is none.
+func scopedTerms(ctx context.Context, fp *Failpoint) *terms {
+	for s, _ := ctx.Value(scopeKey{}).(*scope); s != nil; s = s.parent {
+		if s.fp != fp || !s.active() {
+			continue
+		}
+		s.mu.RLock()
+		t := s.t
+		s.mu.RUnlock()
+		if t != nil {
+			return t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnableFor(t *testing.T) {
+	defer clearGlobalVars()
+
+	fp := NewFailpoint("failpoint")
+	bg := context.Background()
+
+	ctx1, err := EnableFor(bg, "failpoint", "return(1)")
+	require.NoError(t, err)
+	ctx2, err := EnableFor(bg, "failpoint", "return(2)")
+	require.NoError(t, err)
+
+	// each context only sees its own terms, and the rest of the process none
+	v, err := fp.AcquireContext(ctx1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+	v, err = fp.AcquireContext(ctx2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, v)
+	_, err = fp.AcquireContext(bg)
+	assert.ErrorIs(t, err, ErrDisabled)
+	_, err = fp.Acquire()
+	assert.ErrorIs(t, err, ErrDisabled)
+
+	// the terms set by Enable are seen outside of the scopes
+	require.NoError(t, Enable("failpoint", "return(3)"))
+	v, err = fp.AcquireContext(bg)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+
+	// scopes nest, and disabling the innermost reveals the enclosing one
+	type key struct{}
+	ctx3, err := EnableFor(context.WithValue(ctx1, key{}, 0), "failpoint", "return(4)")
+	require.NoError(t, err)
+	v, err = fp.AcquireContext(ctx3)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, v)
+	require.NoError(t, DisableFor(ctx3, "failpoint"))
+	v, err = fp.AcquireContext(ctx3)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+	require.NoError(t, DisableFor(ctx3, "failpoint"))
+	v, err = fp.AcquireContext(ctx3)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+	assert.ErrorIs(t, DisableFor(ctx3, "failpoint"), ErrDisabled)
+
+	_, err = EnableFor(bg, "unknown", "return(1)")
+	assert.ErrorIs(t, err, ErrNoExist)
+	_, err = EnableFor(bg, "failpoint", "retrun(1)")
+	assert.ErrorIs(t, err, ErrBadParse)
+}
+
+func TestEnableForWakesUpWhenDone(t *testing.T) {
+	defer clearGlobalVars()
+
+	fp := NewFailpoint("failpoint")
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx, err := EnableFor(ctx, "failpoint", "pause")
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fp.AcquireContext(ctx)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pause was not interrupted when the context was done")
+	}
+}
+
+func TestEnableForRemovedWhenDone(t *testing.T) {
+	defer clearGlobalVars()
+
+	fp := NewFailpoint("failpoint")
+	require.NoError(t, Enable("failpoint", "return(1)"))
+	outer, err := EnableFor(context.Background(), "failpoint", "return(2)")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(outer)
+	inner, err := EnableFor(ctx, "failpoint", "return(3)")
+	require.NoError(t, err)
+	v, err := fp.AcquireContext(inner)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+
+	// the scope no longer applies as soon as its context is done
+	cancel()
+	v, err = fp.AcquireContext(inner)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, v)
+	require.NoError(t, DisableFor(inner, "failpoint"))
+	v, err = fp.AcquireContext(inner)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+	assert.ErrorIs(t, DisableFor(inner, "failpoint"), ErrDisabled)
+}
@@ -0,0 +1,61 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"math/rand"
+	"time"
+)
+
+// delay is a randomized duration for the sleep action.
+type delay interface {
+	sample(r *rand.Rand) time.Duration
+}
+
+// delayRange is uniformly distributed in [min, max].
+type delayRange struct{ min, max time.Duration }
+
+func (d delayRange) String() string { return d.min.String() + ".." + d.max.String() }
+
+func (d delayRange) sample(r *rand.Rand) time.Duration {
+	if d.max == d.min {
+		return d.min
+	}
+	return d.min + time.Duration(r.Int63n(int64(d.max-d.min)+1))
+}
+
+// delayExp is exponentially distributed with the given mean.
+type delayExp struct{ mean time.Duration }
+
+func (d delayExp) String() string { return "exp(" + d.mean.String() + ")" }
+
+func (d delayExp) sample(r *rand.Rand) time.Duration {
+	return time.Duration(r.ExpFloat64() * float64(d.mean))
+}
+
+// delayNormal is normally distributed, cut off at zero.
+type delayNormal struct{ mean, stddev time.Duration }
+
+func (d delayNormal) String() string {
+	return "normal(" + d.mean.String() + "," + d.stddev.String() + ")"
+}
+
+func (d delayNormal) sample(r *rand.Rand) time.Duration {
+	v := time.Duration(r.NormFloat64()*float64(d.stddev)) + d.mean
+	if v < 0 {
+		return 0
+	}
+	return v
+}
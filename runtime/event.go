@@ -0,0 +1,185 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	goruntime "runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventType tells what happened to a failpoint.
+type EventType int
+
+const (
+	// EventTrigger is sent when a term of a failpoint is executed.
+	EventTrigger EventType = iota
+	// EventEnable is sent when a failpoint is enabled, or its terms replaced.
+	EventEnable
+	// EventDisable is sent when a failpoint is disabled.
+	EventDisable
+)
+
+func (et EventType) String() string {
+	switch et {
+	case EventTrigger:
+		return "trigger"
+	case EventEnable:
+		return "enable"
+	case EventDisable:
+		return "disable"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes something that happened to a failpoint.
+type Event struct {
+	Type EventType
+	// Name is the full name of the failpoint.
+	Name string
+	// Term is the term executed by a trigger, or the terms set by an enable.
+	Term string
+	// Value is the value the failpoint returns, such as the value given to
+	// return, the *FailpointError of error, or nil for off. For the actions
+	// which block or don't return, sleep, pause, panic and break, it is their
+	// argument instead, struct{}{} when there is none.
+	Value interface{}
+	// Time is when the event happened.
+	Time time.Time
+	// Goroutine is the ID of the goroutine which executed the term.
+	Goroutine int64
+	// Stack is the stack trace of the goroutine which executed the term, for
+	// subscribers asking for it with WithStack.
+	Stack []byte
+}
+
+// SubscribeOption configures a subscriber to the failpoint events.
+type SubscribeOption func(*subscriber)
+
+// WithStack has the stack trace of the triggering goroutine included in the
+// trigger events.
+func WithStack() SubscribeOption {
+	return func(s *subscriber) { s.stack = true }
+}
+
+type subscriber struct {
+	fn    func(Event)
+	stack bool
+}
+
+var (
+	// subscribers to the events, protected by subscribersMu; nSubscribers
+	// spares failpoints the lock while there is no subscriber
+	subscribers   []*subscriber
+	subscribersMu sync.RWMutex
+	nSubscribers  atomic.Int32
+)
+
+// Subscribe calls fn for every event happening to the failpoints, until the
+// returned function is called. fn is called synchronously by the goroutine
+// executing a term, right after the action runs, or right before for the
+// actions which block or don't return, so it must not block; it may be called
+// concurrently.
+func Subscribe(fn func(Event), opts ...SubscribeOption) (cancel func()) {
+	s := &subscriber{fn: fn}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	subscribersMu.Lock()
+	subscribers = append(subscribers, s)
+	nSubscribers.Add(1)
+	subscribersMu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			subscribersMu.Lock()
+			defer subscribersMu.Unlock()
+			for i := range subscribers {
+				if subscribers[i] == s {
+					subscribers = append(subscribers[:i:i], subscribers[i+1:]...)
+					nSubscribers.Add(-1)
+					break
+				}
+			}
+		})
+	}
+}
+
+// notify sends an event to the subscribers; it is called without holding
+// any lock of the runtime.
+func notify(e Event) {
+	if nSubscribers.Load() == 0 {
+		return
+	}
+	subscribersMu.RLock()
+	subs := subscribers
+	subscribersMu.RUnlock()
+
+	e.Time = time.Now()
+	var stack []byte
+	if e.Type == EventTrigger {
+		e.Goroutine = currentGoroutineID()
+		// the whole stack trace is costly, only take it when asked for
+		for _, s := range subs {
+			if s.stack {
+				stack = currentStack()
+				break
+			}
+		}
+	}
+	for _, s := range subs {
+		se := e
+		if s.stack {
+			se.Stack = stack
+		}
+		s.fn(se)
+	}
+}
+
+func currentStack() []byte {
+	buf := make([]byte, 4096)
+	for {
+		n := goruntime.Stack(buf, false)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// currentGoroutineID gives the ID of the calling goroutine, from the first
+// line of its stack trace only.
+func currentGoroutineID() int64 {
+	var buf [64]byte
+	n := goruntime.Stack(buf[:], false)
+	return goroutineID(buf[:n])
+}
+
+// goroutineID parses the ID of the goroutine from the first line of its
+// stack trace, "goroutine 42 [running]:".
+func goroutineID(stack []byte) int64 {
+	s := strings.TrimPrefix(string(stack), "goroutine ")
+	if i := strings.IndexByte(s, ' '); i > 0 {
+		id, _ := strconv.ParseInt(s[:i], 10, 64)
+		return id
+	}
+	return 0
+}
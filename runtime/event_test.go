@@ -0,0 +1,138 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribe(t *testing.T) {
+	defer clearGlobalVars()
+
+	fp := NewFailpoint("go.etcd.io/gofail/runtime.failpoint")
+
+	var mu sync.Mutex
+	var events, stacked []Event
+	cancel := Subscribe(func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	})
+	cancelStacked := Subscribe(func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		stacked = append(stacked, e)
+	}, WithStack())
+	defer cancelStacked()
+
+	require.NoError(t, Enable("failpoint", `1*return("abc")->off`))
+	fp.Acquire()
+	fp.Acquire()
+	require.NoError(t, Disable("failpoint"))
+	cancel()
+	require.NoError(t, Enable("failpoint", `off`))
+
+	require.Len(t, events, 4)
+	for _, e := range events {
+		assert.Equal(t, "go.etcd.io/gofail/runtime.failpoint", e.Name)
+		assert.False(t, e.Time.IsZero())
+		assert.Nil(t, e.Stack)
+	}
+	assert.Equal(t, EventEnable, events[0].Type)
+	assert.Equal(t, `1*return("abc")->off`, events[0].Term)
+	assert.Equal(t, EventTrigger, events[1].Type)
+	assert.Equal(t, `1*return("abc")`, events[1].Term)
+	assert.Equal(t, "abc", events[1].Value)
+	assert.NotZero(t, events[1].Goroutine)
+	assert.Equal(t, EventTrigger, events[2].Type)
+	assert.Equal(t, `off`, events[2].Term)
+	assert.Nil(t, events[2].Value)
+	assert.Equal(t, EventDisable, events[3].Type)
+
+	// only the subscriber asking for it gets the stack trace
+	require.Len(t, stacked, 5)
+	assert.Equal(t, events[1].Goroutine, stacked[1].Goroutine)
+	assert.True(t, strings.Contains(string(stacked[1].Stack), "TestSubscribe"), string(stacked[1].Stack))
+	assert.Nil(t, stacked[3].Stack)
+}
+
+func TestGoroutineID(t *testing.T) {
+	assert.Equal(t, int64(42), goroutineID([]byte("goroutine 42 [running]:\nmain.main()")))
+	assert.Equal(t, int64(0), goroutineID([]byte("garbage")))
+	assert.NotZero(t, currentGoroutineID())
+	assert.Equal(t, goroutineID(currentStack()), currentGoroutineID())
+}
+
+func TestSubscribeValue(t *testing.T) {
+	defer clearGlobalVars()
+
+	fp := NewFailpoint("failpoint")
+	var e Event
+	cancel := Subscribe(func(ev Event) { e = ev })
+	defer cancel()
+
+	// the value returned, or the argument of the actions which block
+	for _, tt := range []struct {
+		terms    string
+		expected interface{}
+	}{
+		{`return("abc")`, "abc"},
+		{`return`, struct{}{}},
+		{`off`, nil},
+		{`print`, nil},
+		{`sleep("1ms")`, "1ms"},
+	} {
+		require.NoError(t, Enable("failpoint", tt.terms))
+		fp.Acquire()
+		assert.Equal(t, EventTrigger, e.Type, tt.terms)
+		assert.Equal(t, tt.expected, e.Value, tt.terms)
+	}
+
+	require.NoError(t, Enable("failpoint", `error("boom")`))
+	v, err := fp.Acquire()
+	require.NoError(t, err)
+	var fe *FailpointError
+	require.ErrorAs(t, e.Value.(error), &fe)
+	assert.Equal(t, "boom", fe.Error())
+	assert.Same(t, v, e.Value)
+}
+
+func TestSubscribeRegister(t *testing.T) {
+	defer clearGlobalVars()
+
+	var mu sync.Mutex
+	var events []Event
+	cancel := Subscribe(func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	})
+	defer cancel()
+
+	// failpoints getting terms as they are registered are reported enabled
+	require.NoError(t, loadFailpoints("failpoint1=return(1);failpoint*=return(2)"))
+	NewFailpoint("go.etcd.io/gofail/runtime.failpoint1")
+	NewFailpoint("go.etcd.io/gofail/runtime.failpoint2")
+	NewFailpoint("go.etcd.io/gofail/runtime.other")
+
+	require.Len(t, events, 2)
+	assert.Equal(t, Event{Type: EventEnable, Name: "go.etcd.io/gofail/runtime.failpoint1", Term: "return(1)", Time: events[0].Time}, events[0])
+	assert.Equal(t, Event{Type: EventEnable, Name: "go.etcd.io/gofail/runtime.failpoint2", Term: "return(2)", Time: events[1].Time}, events[1])
+}
@@ -15,17 +15,53 @@
 package runtime
 
 import (
+	"context"
 	"fmt"
+	goruntime "runtime"
+	"strconv"
 	"sync"
+	"time"
 )
 
 type Failpoint struct {
-	t   *terms
-	mux sync.RWMutex
+	// name is the full name of the failpoint
+	name string
+	// varType is the type declared by the gofail comment, if known
+	varType string
+	// site is the file and line the failpoint was registered at
+	site string
+	t    *terms
+	mux  sync.RWMutex
 }
 
+// NewFailpoint registers a failpoint. The code generated by gofail names it
+// after the import path of its package and its name in the gofail comment,
+// e.g. "go.etcd.io/etcd/server/wal.Sync", so that failpoints of different
+// packages don't collide.
 func NewFailpoint(name string) *Failpoint {
-	return register(name)
+	return register(name, "", callerSite())
+}
+
+// NewTypedFailpoint is like NewFailpoint, but records the type declared by
+// the gofail comment, e.g. "string" for "// gofail: var Sync string", and the
+// file and line of the comment, e.g. "go.etcd.io/etcd/server/wal/wal.go:42",
+// as reported by the HTTP endpoint. An empty site stands for the caller. The
+// code generated by gofail calls it.
+func NewTypedFailpoint(name, varType, site string) *Failpoint {
+	if len(site) == 0 {
+		site = callerSite()
+	}
+	return register(name, varType, site)
+}
+
+// callerSite gives the file and line of the code calling the function that
+// calls it.
+func callerSite() string {
+	_, file, line, ok := goruntime.Caller(2)
+	if !ok {
+		return ""
+	}
+	return file + ":" + strconv.Itoa(line)
 }
 
 // Acquire gets evalutes the failpoint terms; if the failpoint
@@ -35,14 +71,33 @@ func NewFailpoint(name string) *Failpoint {
 // but the already in-flight execution won't be terminated
 func (fp *Failpoint) Acquire() (interface{}, error) {
 	fp.mux.RLock()
-	// terms are locked during execution, so deepcopy is not required as no change can be made during execution
+	// no copy is needed: Enable and Disable replace the terms rather than
+	// change them, and stop the old ones, so an in-flight evaluation keeps
+	// a consistent chain; the state changed by the evaluation is protected by
+	// the terms' own lock, and actions, which run without it, only read the
+	// immutable parts of their term or take the lock themselves
 	cachedT := fp.t
 	fp.mux.RUnlock()
 
-	if cachedT == nil {
+	return acquire(cachedT)
+}
+
+// AcquireContext is like Acquire, but evaluates the terms set by EnableFor
+// for ctx when there are any, rather than those set by Enable. The code
+// generated for a failpoint declared with a "ctx=<expr>" annotation, such as
+// "// gofail: var X string ctx=ctx", calls it.
+func (fp *Failpoint) AcquireContext(ctx context.Context) (interface{}, error) {
+	if t := scopedTerms(ctx, fp); t != nil {
+		return acquire(t)
+	}
+	return fp.Acquire()
+}
+
+func acquire(t *terms) (interface{}, error) {
+	if t == nil {
 		return nil, ErrDisabled
 	}
-	result := cachedT.eval()
+	result := t.eval()
 	if result == nil {
 		return nil, ErrDisabled
 	}
@@ -51,13 +106,16 @@ func (fp *Failpoint) Acquire() (interface{}, error) {
 
 // BadType is called when the failpoint evaluates to the wrong type.
 func (fp *Failpoint) BadType(v interface{}, t string) {
-	fmt.Printf("failpoint: %q got value %v of type \"%T\" but expected type %q\n", fp.t.fpath, v, v, t)
+	fmt.Printf("failpoint: %q got value %v of type \"%T\" but expected type %q\n", fp.name, v, v, t)
 }
 
 func (fp *Failpoint) SetTerm(t *terms) {
 	fp.mux.Lock()
 	defer fp.mux.Unlock()
 
+	if fp.t != nil {
+		fp.t.stop()
+	}
 	fp.t = t
 }
 
@@ -68,11 +126,37 @@ func (fp *Failpoint) ClearTerm() error {
 	if fp.t == nil {
 		return ErrDisabled
 	}
+	fp.t.stop()
 	fp.t = nil
 
 	return nil
 }
 
+// Release wakes up the goroutines paused at the failpoint.
+func (fp *Failpoint) Release() error {
+	fp.mux.RLock()
+	defer fp.mux.RUnlock()
+
+	if fp.t == nil {
+		return ErrDisabled
+	}
+	fp.t.releasePaused()
+
+	return nil
+}
+
+// Paused gives the number of goroutines currently paused at the failpoint.
+func (fp *Failpoint) Paused() (int, error) {
+	fp.mux.RLock()
+	defer fp.mux.RUnlock()
+
+	if fp.t == nil {
+		return 0, ErrDisabled
+	}
+
+	return fp.t.pausedCount(), nil
+}
+
 func (fp *Failpoint) Status() (string, int, error) {
 	fp.mux.RLock()
 	defer fp.mux.RUnlock()
@@ -84,3 +168,102 @@ func (fp *Failpoint) Status() (string, int, error) {
 
 	return t.desc, t.counter, nil
 }
+
+// FailpointStats are the statistics of the terms of a failpoint, since they
+// were enabled or their counts were reset.
+type FailpointStats struct {
+	// Terms is the terms of the failpoint.
+	Terms string
+	// Evaluations is the number of times the failpoint was reached, whether
+	// a term was executed or not.
+	Evaluations int
+	// Hits is the number of times a term was executed.
+	Hits int
+	// FirstHit and LastHit are when a term was first and last executed; they
+	// are zero if none was.
+	FirstHit, LastHit time.Time
+	// PerTerm are the statistics of each term of the "->" chain.
+	PerTerm []TermStats
+}
+
+// TermStats are the statistics of a term of a failpoint.
+type TermStats struct {
+	// Term is the term, in its canonical form.
+	Term string
+	// Hits is the number of times the term was executed.
+	Hits int
+	// Remaining is the number of executions left to the N* modifier of the
+	// term, or -1 if it has none.
+	Remaining int
+	// FirstHit and LastHit are when the term was first and last executed.
+	FirstHit, LastHit time.Time
+}
+
+// Stats gives the statistics of the failpoint.
+func (fp *Failpoint) Stats() (*FailpointStats, error) {
+	fp.mux.RLock()
+	defer fp.mux.RUnlock()
+
+	if fp.t == nil {
+		return nil, ErrDisabled
+	}
+
+	return fp.t.stats(), nil
+}
+
+// ResetCounts zeroes the counts of the failpoint without enabling it again,
+// so the state of its terms, such as the executions left to N* modifiers, is
+// kept.
+func (fp *Failpoint) ResetCounts() error {
+	fp.mux.RLock()
+	defer fp.mux.RUnlock()
+
+	if fp.t == nil {
+		return ErrDisabled
+	}
+	fp.t.resetCounts()
+
+	return nil
+}
+
+// FailpointInfo describes a registered failpoint, as listed by the /v2/ HTTP
+// API.
+type FailpointInfo struct {
+	Name string `json:"name"`
+	// Type is the type declared by the gofail comment, or empty if the
+	// failpoint was registered by NewFailpoint.
+	Type string `json:"type,omitempty"`
+	// Site is the file and line of the gofail comment declaring the
+	// failpoint, or of the code registering it with NewFailpoint.
+	Site    string `json:"site,omitempty"`
+	Enabled bool   `json:"enabled"`
+	Terms   string `json:"terms,omitempty"`
+	// Count is the number of times a term was executed, as given by Status.
+	Count int `json:"count"`
+	// Evaluations is the number of times the failpoint was reached.
+	Evaluations int `json:"evaluations"`
+	// Sleeping and Paused are the numbers of goroutines currently blocked
+	// by sleep and pause actions.
+	Sleeping int `json:"sleeping"`
+	Paused   int `json:"paused"`
+}
+
+// Info describes the failpoint, whether it is enabled or not.
+func (fp *Failpoint) Info() *FailpointInfo {
+	fp.mux.RLock()
+	t := fp.t
+	fp.mux.RUnlock()
+
+	info := &FailpointInfo{Name: fp.name, Type: fp.varType, Site: fp.site}
+	if t == nil {
+		return info
+	}
+	st := t.stats()
+	info.Enabled = true
+	info.Terms = st.Terms
+	info.Count = st.Hits
+	info.Evaluations = st.Evaluations
+	info.Sleeping = t.sleepingCount()
+	info.Paused = t.pausedCount()
+	return info
+}
@@ -15,7 +15,9 @@
 package runtime
 
 import (
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -46,9 +48,206 @@ func TestSameFailpointCreateTwice(t *testing.T) {
 	assert.Panics(t, func() { NewFailpoint("failpoint") })
 }
 
+func TestFailpointQualifiedNames(t *testing.T) {
+	envTerms = map[string]string{"Flush": "return(1)"}
+	defer clearGlobalVars()
+
+	walSync := NewFailpoint("go.etcd.io/etcd/server/wal.Sync")
+	NewFailpoint("go.etcd.io/etcd/server/storage.Sync")
+	walFlush := NewFailpoint("go.etcd.io/etcd/server/wal.Flush")
+	assert.NotPanics(t, func() { NewFailpoint("Sync") })
+
+	// a short name from GOFAIL_FAILPOINTS applies when registering
+	v, err := walFlush.Acquire()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+
+	// the short name of a failpoint reaches it when it is unique
+	assert.Nil(t, Enable("Flush", "return(2)"))
+	v, err = walFlush.Acquire()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, v)
+
+	// the full name always does
+	assert.Nil(t, Enable("go.etcd.io/etcd/server/wal.Sync", "return(3)"))
+	v, err = walSync.Acquire()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, v)
+
+	// "Sync" is both the full name of a failpoint and a short name of others
+	assert.Nil(t, Enable("Sync", "return(4)"))
+	v, err = walSync.Acquire()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, v)
+
+	NewFailpoint("go.etcd.io/raft.Flush")
+	err = Enable("Flush", "return(5)")
+	assert.ErrorIs(t, err, ErrAmbiguous)
+	assert.Equal(t, `failpoint: failpoint name is ambiguous: "Flush" may be any of go.etcd.io/etcd/server/wal.Flush, go.etcd.io/raft.Flush`, err.Error())
+	assert.ErrorIs(t, Disable("Unknown"), ErrNoExist)
+}
+
+func TestFailpointPauseAndRelease(t *testing.T) {
+	defer clearGlobalVars()
+
+	fp := NewFailpoint("failpoint")
+	assert.Nil(t, Enable("failpoint", "pause"))
+
+	var wg sync.WaitGroup
+	acquire := func() {
+		defer wg.Done()
+		fp.Acquire()
+	}
+	waitPaused := func(n int) {
+		assert.Eventually(t, func() bool {
+			paused, err := Paused("failpoint")
+			return err == nil && paused == n
+		}, time.Second, time.Millisecond)
+	}
+
+	wg.Add(2)
+	go acquire()
+	go acquire()
+	waitPaused(2)
+
+	assert.Nil(t, Release("failpoint"))
+	wg.Wait()
+	waitPaused(0)
+
+	// goroutines reaching the failpoint after a release are paused again
+	wg.Add(1)
+	go acquire()
+	waitPaused(1)
+
+	// disabling the failpoint releases everyone
+	assert.Nil(t, Disable("failpoint"))
+	wg.Wait()
+
+	assert.ErrorIs(t, Release("failpoint"), ErrDisabled)
+	assert.ErrorIs(t, Release("nonexistent"), ErrNoExist)
+}
+
+func TestFailpointDisableInterruptsSleep(t *testing.T) {
+	defer clearGlobalVars()
+
+	fp := NewFailpoint("failpoint")
+	assert.Nil(t, Enable("failpoint", `sleep("1h")`))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fp.Acquire()
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	assert.Nil(t, Disable("failpoint"))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("sleep was not interrupted by Disable")
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	defer clearGlobalVars()
+	defer func() {
+		shutdown = make(chan struct{})
+		shutdownOnce = sync.Once{}
+	}()
+
+	sleepFp := NewFailpoint("sleepFailpoint")
+	pauseFp := NewFailpoint("pauseFailpoint")
+	assert.Nil(t, Enable("sleepFailpoint", `sleep("1h")`))
+	assert.Nil(t, Enable("pauseFailpoint", `pause`))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		sleepFp.Acquire()
+	}()
+	go func() {
+		defer wg.Done()
+		pauseFp.Acquire()
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	Shutdown()
+	wg.Wait()
+
+	// once shut down, sleep and pause don't block anymore
+	start := time.Now()
+	sleepFp.Acquire()
+	pauseFp.Acquire()
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestSetSeedRestartsEnabledFailpoints(t *testing.T) {
+	defer clearGlobalVars()
+	defer SetSeed(Seed())
+
+	fp := NewFailpoint("failpoint")
+	assert.Nil(t, Enable("failpoint", `50.0%return(1)`))
+	acquireN := func() []error {
+		var errs []error
+		for i := 0; i < 64; i++ {
+			_, err := fp.Acquire()
+			errs = append(errs, err)
+		}
+		return errs
+	}
+
+	SetSeed(42)
+	first := acquireN()
+	SetSeed(42)
+	assert.Equal(t, first, acquireN())
+}
+
 // clearGlobalVars will unset runtime package global variables
 // note: doesn't work if tests are run in parallel
 func clearGlobalVars() {
 	envTerms = make(map[string]string)
 	failpoints = make(map[string]*Failpoint)
+	shortNames = make(map[string][]string)
+	patterns = nil
+	configPath, config, configData = "", nil, nil
+}
+
+func TestFailpointStats(t *testing.T) {
+	defer clearGlobalVars()
+
+	fp := NewFailpoint("failpoint")
+	_, err := Stats("failpoint")
+	assert.ErrorIs(t, err, ErrDisabled)
+
+	assert.Nil(t, Enable("failpoint", `every(2)2*return(1)->after(10)off`))
+	for i := 0; i < 5; i++ {
+		fp.Acquire()
+	}
+	st, err := Stats("failpoint")
+	assert.Nil(t, err)
+	assert.Equal(t, `every(2)2*return(1)->after(10)off`, st.Terms)
+	assert.Equal(t, 5, st.Evaluations)
+	assert.Equal(t, 2, st.Hits)
+	assert.False(t, st.FirstHit.IsZero())
+	assert.False(t, st.LastHit.Before(st.FirstHit))
+	assert.Len(t, st.PerTerm, 2)
+	assert.Equal(t, TermStats{Term: `every(2)2*return(1)`, Hits: 2, Remaining: 0, FirstHit: st.FirstHit, LastHit: st.LastHit}, st.PerTerm[0])
+	assert.Equal(t, TermStats{Term: `after(10)off`, Remaining: -1}, st.PerTerm[1])
+
+	// resetting the counts keeps the state of the terms
+	assert.Nil(t, Enable("failpoint", `2*return(1)`))
+	fp.Acquire()
+	assert.Nil(t, ResetCounts("failpoint"))
+	st, err = Stats("failpoint")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, st.Evaluations)
+	assert.Equal(t, 0, st.Hits)
+	assert.True(t, st.FirstHit.IsZero())
+	assert.Equal(t, TermStats{Term: `2*return(1)`, Remaining: 1}, st.PerTerm[0])
+	_, count, err := Status("failpoint")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	assert.ErrorIs(t, ResetCounts("unknown"), ErrNoExist)
 }
@@ -15,28 +15,111 @@
 package runtime
 
 import (
+	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"os"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 )
 
-type httpHandler struct{}
+type httpHandler struct {
+	// token and readOnlyToken are the bearer tokens given by
+	// GOFAIL_HTTP_TOKEN and GOFAIL_HTTP_READONLY_TOKEN, see authorize
+	token, readOnlyToken string
+}
+
+var (
+	// unixListener is the listener of the HTTP endpoint when it is served on
+	// a Unix domain socket, closed by Shutdown to remove the socket file; it
+	// is protected by unixListenerMu
+	unixListener   net.Listener
+	unixListenerMu sync.Mutex
+)
 
+// serve serves the HTTP endpoint on a TCP address such as "127.0.0.1:1234",
+// or on a Unix domain socket such as "unix:///run/app/gofail.sock".
+//
+// Requests must carry the bearer tokens set by GOFAIL_HTTP_TOKEN and
+// GOFAIL_HTTP_READONLY_TOKEN, if any, and the endpoint is served over TLS
+// with the files named by GOFAIL_HTTP_TLS_CERT, GOFAIL_HTTP_TLS_KEY and
+// GOFAIL_HTTP_TLS_CA, if set.
 func serve(host string) error {
-	ln, err := net.Listen("tcp", host)
+	h := &httpHandler{
+		token:         os.Getenv("GOFAIL_HTTP_TOKEN"),
+		readOnlyToken: os.Getenv("GOFAIL_HTTP_READONLY_TOKEN"),
+	}
+	tlsConf, err := tlsConfig(os.Getenv("GOFAIL_HTTP_TLS_CERT"), os.Getenv("GOFAIL_HTTP_TLS_KEY"), os.Getenv("GOFAIL_HTTP_TLS_CA"))
 	if err != nil {
 		return err
 	}
-	go http.Serve(ln, &httpHandler{})
+
+	var ln net.Listener
+	if path, ok := strings.CutPrefix(host, "unix://"); ok {
+		if ln, err = listenUnix(path); err != nil {
+			return err
+		}
+		unixListenerMu.Lock()
+		unixListener = ln
+		unixListenerMu.Unlock()
+	} else if ln, err = net.Listen("tcp", host); err != nil {
+		return err
+	}
+	if tlsConf != nil {
+		ln = tls.NewListener(ln, tlsConf)
+	}
+	go http.Serve(ln, h)
 	return nil
 }
 
-func (*httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+// listenUnix listens on a Unix domain socket which only the user running the
+// process may connect to. A socket left behind by a previous process is
+// removed, unless something still listens on it.
+func listenUnix(path string) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("failpoint: %s exists and is not a socket", path)
+		}
+		if c, err := net.Dial("unix", path); err == nil {
+			c.Close()
+			return nil, fmt.Errorf("failpoint: %s is in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	// no one may connect before the permissions are set
+	old := umask(0o177)
+	ln, err := net.Listen("unix", path)
+	umask(old)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+
+	// the stream of events lasts until the client goes away, so it must not
+	// hold panicMu
+	if r.Method == "GET" && r.URL.Path == "/events" {
+		serveEvents(w, r)
+		return
+	}
+
 	// Ensures the server(runtime) doesn't panic due to the execution of
 	// panic failpoints during processing of the HTTP request, as the
 	// sender of the HTTP request should not be affected by the execution
@@ -56,6 +139,28 @@ func (*httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	key = key[1:]
 
 	switch {
+	// the JSON API, see serveV2
+	case strings.HasPrefix(key, "v2/"):
+		serveV2(w, r, strings.TrimPrefix(r.URL.Path, "/v2/"))
+
+	// checkpoints the state of all the failpoints
+	case r.Method == "GET" && key == "snapshot":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Snapshot())
+
+	// puts back the state of all the failpoints
+	case r.Method == "PUT" && key == "snapshot":
+		var snap State
+		if err := json.NewDecoder(r.Body).Decode(&snap); err != nil {
+			http.Error(w, "fail to parse snapshot: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := Restore(&snap); err != nil {
+			http.Error(w, "fail to restore snapshot: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
 	// sets the failpoint
 	case r.Method == "PUT":
 		v, err := io.ReadAll(r.Body)
@@ -64,26 +169,37 @@ func (*httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		fpMap := map[string]string{key: string(v)}
 		if strings.EqualFold(key, "failpoints") {
-			fpMap, err = parseFailpoints(string(v))
+			fps, err := parseFailpoints(string(v))
 			if err != nil {
 				http.Error(w, fmt.Sprintf("fail to parse failpoint: %v", err), http.StatusBadRequest)
 				return
 			}
-		}
-
-		for k, v := range fpMap {
-			if err := Enable(k, v); err != nil {
+			// in the order given, as for GOFAIL_FAILPOINTS
+			if err := update(fps); err != nil {
 				http.Error(w, fmt.Sprintf("fail to set failpoint: %v", err), http.StatusBadRequest)
 				return
 			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		enable := Enable
+		if isPattern(key) {
+			enable = EnablePattern
+		}
+		if err := enable(key, string(v)); err != nil {
+			http.Error(w, fmt.Sprintf("fail to set failpoint: %v", err), http.StatusBadRequest)
+			return
 		}
 		w.WriteHeader(http.StatusNoContent)
 
 	// gets status of the failpoint
 	case r.Method == "GET":
 		if len(key) == 0 {
+			// the seed lets a run relying on probabilities be replayed
+			// with GOFAIL_SEED
+			w.Header().Set("Gofail-Seed", strconv.FormatInt(Seed(), 10))
 			fps := list()
 			sort.Strings(fps)
 			lines := make([]string, len(fps))
@@ -92,6 +208,18 @@ func (*httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				lines[i] = fps[i] + "=" + s
 			}
 			w.Write([]byte(strings.Join(lines, "\n") + "\n"))
+		} else if strings.HasSuffix(key, "/paused") {
+			fp := key[:len(key)-len("/paused")]
+			paused, err := Paused(fp)
+			if err != nil {
+				if errors.Is(err, ErrNoExist) {
+					http.Error(w, "failed to GET: "+err.Error(), http.StatusNotFound)
+				} else {
+					http.Error(w, "failed to GET: "+err.Error(), http.StatusInternalServerError)
+				}
+				return
+			}
+			w.Write([]byte(strconv.Itoa(paused)))
 		} else if strings.HasSuffix(key, "/count") {
 			fp := key[:len(key)-len("/count")]
 			_, count, err := Status(fp)
@@ -112,9 +240,39 @@ func (*httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			w.Write([]byte(status + "\n"))
 		}
 
+	// releases the goroutines paused at the failpoint
+	case r.Method == "POST" && strings.HasSuffix(key, "/release"):
+		fp := key[:len(key)-len("/release")]
+		if err := Release(fp); err != nil {
+			if errors.Is(err, ErrNoExist) {
+				http.Error(w, "failed to release failpoint "+err.Error(), http.StatusNotFound)
+			} else {
+				http.Error(w, "failed to release failpoint "+err.Error(), http.StatusBadRequest)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	// resets the counts of the failpoint
+	case r.Method == "DELETE" && strings.HasSuffix(key, "/count"):
+		fp := key[:len(key)-len("/count")]
+		if err := ResetCounts(fp); err != nil {
+			if errors.Is(err, ErrNoExist) {
+				http.Error(w, "failed to reset counts "+err.Error(), http.StatusNotFound)
+			} else {
+				http.Error(w, "failed to reset counts "+err.Error(), http.StatusBadRequest)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
 	// deactivates a failpoint
 	case r.Method == "DELETE":
-		if err := Disable(key); err != nil {
+		disable := Disable
+		if isPattern(key) {
+			disable = DisablePattern
+		}
+		if err := disable(key); err != nil {
 			http.Error(w, "failed to delete failpoint "+err.Error(), http.StatusBadRequest)
 			return
 		}
@@ -123,6 +281,7 @@ func (*httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Allow", "DELETE")
 		w.Header().Add("Allow", "GET")
 		w.Header().Set("Allow", "PUT")
+		w.Header().Add("Allow", "POST")
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
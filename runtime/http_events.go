@@ -0,0 +1,139 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// eventsBuffer is the number of events kept for a slow client of
+	// GET /events before they are dropped.
+	eventsBuffer = 1024
+	// droppedInterval is how often the dropped events are reported while no
+	// other event is sent.
+	droppedInterval = time.Second
+)
+
+// apiEvent is an event as streamed by GET /events.
+type apiEvent struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Term string `json:"term,omitempty"`
+	// Value is the value of a trigger, see Event, formatted with %v.
+	Value     string    `json:"value,omitempty"`
+	Time      time.Time `json:"time"`
+	Goroutine int64     `json:"goroutine,omitempty"`
+}
+
+// serveEvents streams the events of the failpoints as Server-Sent Events, as
+// they happen, until the client goes away or Shutdown. The "name" parameters
+// of the query, if any, keep the events of the failpoints matching one of
+// them, by full or short name or as a pattern.
+//
+// Failpoints don't wait for a client: when one doesn't read the events fast
+// enough, they are dropped, and the client is told how many with a "dropped"
+// event, before the next event or within droppedInterval, and at Shutdown.
+func serveEvents(w http.ResponseWriter, r *http.Request) {
+	var filters []*pattern
+	for _, name := range r.URL.Query()["name"] {
+		p, err := newPattern(name, "")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filters = append(filters, p)
+	}
+	f, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	events := make(chan Event, eventsBuffer)
+	var dropped atomic.Int64
+	cancel := Subscribe(func(e Event) {
+		if !matchAny(filters, e.Name) {
+			return
+		}
+		select {
+		case events <- e:
+		default:
+			dropped.Add(1)
+		}
+	})
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	f.Flush()
+
+	reportDropped := func() bool {
+		if n := dropped.Swap(0); n > 0 {
+			if _, err := fmt.Fprintf(w, "event: dropped\ndata: {\"count\":%d}\n\n", n); err != nil {
+				return false
+			}
+			f.Flush()
+		}
+		return true
+	}
+	ticker := time.NewTicker(droppedInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case e := <-events:
+			if !reportDropped() {
+				return
+			}
+			ae := apiEvent{Type: e.Type.String(), Name: e.Name, Term: e.Term, Time: e.Time, Goroutine: e.Goroutine}
+			if _, ok := e.Value.(struct{}); !ok && e.Value != nil {
+				ae.Value = fmt.Sprint(e.Value)
+			}
+			data, _ := json.Marshal(ae)
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ae.Type, data); err != nil {
+				return
+			}
+			f.Flush()
+		case <-ticker.C:
+			if !reportDropped() {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-shutdown:
+			reportDropped()
+			return
+		}
+	}
+}
+
+// matchAny reports whether a failpoint matches any of the patterns, or
+// whether there is none.
+func matchAny(ps []*pattern, name string) bool {
+	if len(ps) == 0 {
+		return true
+	}
+	for _, p := range ps {
+		if p.match(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPEvents(t *testing.T) {
+	defer clearGlobalVars()
+
+	fp1 := NewFailpoint("go.etcd.io/gofail/examples.failpoint1")
+	fp2 := NewFailpoint("failpoint2")
+	s := httptest.NewServer(&httpHandler{})
+	defer s.Close()
+
+	resp, err := http.Get(s.URL + "/events?name=failpoint1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	require.Eventually(t, func() bool { return nSubscribers.Load() == 1 }, time.Second, time.Millisecond)
+
+	// the stream doesn't keep other requests from being served
+	req, err := http.NewRequest("PUT", s.URL+"/failpoint1", strings.NewReader(`return("abc")`))
+	require.NoError(t, err)
+	put, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	put.Body.Close()
+	require.Equal(t, http.StatusNoContent, put.StatusCode)
+	require.NoError(t, Enable("failpoint2", `return(1)`))
+	fp2.Acquire()
+	fp1.Acquire()
+	require.NoError(t, Disable("failpoint1"))
+
+	sc := bufio.NewScanner(resp.Body)
+	next := func() (string, apiEvent) {
+		t.Helper()
+		var typ string
+		var e apiEvent
+		for sc.Scan() {
+			line := sc.Text()
+			if line == "" {
+				break
+			}
+			if v, ok := strings.CutPrefix(line, "event: "); ok {
+				typ = v
+			} else if v, ok := strings.CutPrefix(line, "data: "); ok {
+				require.NoError(t, json.Unmarshal([]byte(v), &e))
+			}
+		}
+		require.NoError(t, sc.Err())
+		assert.False(t, e.Time.IsZero())
+		e.Time = time.Time{}
+		return typ, e
+	}
+
+	typ, e := next()
+	assert.Equal(t, "enable", typ)
+	assert.Equal(t, apiEvent{Type: "enable", Name: "go.etcd.io/gofail/examples.failpoint1", Term: `return("abc")`}, e)
+	typ, e = next()
+	assert.Equal(t, "trigger", typ)
+	assert.NotZero(t, e.Goroutine)
+	e.Goroutine = 0
+	assert.Equal(t, apiEvent{Type: "trigger", Name: "go.etcd.io/gofail/examples.failpoint1", Term: `return("abc")`, Value: "abc"}, e)
+	typ, _ = next()
+	assert.Equal(t, "disable", typ)
+
+	resp.Body.Close()
+	assert.Eventually(t, func() bool { return nSubscribers.Load() == 0 }, time.Second, time.Millisecond)
+
+	bad, err := http.Get(s.URL + "/events?name=[")
+	require.NoError(t, err)
+	bad.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, bad.StatusCode)
+}
+
+func TestHTTPEventsDropped(t *testing.T) {
+	defer clearGlobalVars()
+	defer func(n int, d time.Duration) { eventsBuffer, droppedInterval = n, d }(eventsBuffer, droppedInterval)
+	eventsBuffer, droppedInterval = 0, 10*time.Millisecond
+
+	NewFailpoint("failpoint")
+	w := &blockingWriter{ResponseRecorder: httptest.NewRecorder(), writing: make(chan struct{}), release: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		serveEvents(w, httptest.NewRequest("GET", "/events", nil).WithContext(ctx))
+	}()
+	require.Eventually(t, func() bool { return nSubscribers.Load() == 1 }, time.Second, time.Millisecond)
+
+	// the stream is stuck writing the first event, so the others are dropped,
+	// and reported even though no event follows
+	require.NoError(t, Enable("failpoint", "return(1)"))
+	<-w.writing
+	for i := 0; i < 3; i++ {
+		require.NoError(t, Enable("failpoint", "return(1)"))
+	}
+	close(w.release)
+	assert.Eventually(t, func() bool {
+		return strings.Contains(w.body(), "event: dropped\ndata: {\"count\":3}\n\n")
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-done
+}
+
+// blockingWriter is a ResponseWriter whose writes wait to be released.
+type blockingWriter struct {
+	*httptest.ResponseRecorder
+	mu      sync.Mutex
+	once    sync.Once
+	writing chan struct{}
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.writing) })
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.ResponseRecorder.Write(p)
+}
+
+func (w *blockingWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.ResponseRecorder.Flush()
+}
+
+func (w *blockingWriter) body() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.Body.String()
+}
@@ -0,0 +1,92 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeUnix(t *testing.T) {
+	defer clearGlobalVars()
+	defer func() {
+		shutdown = make(chan struct{})
+		shutdownOnce = sync.Once{}
+		unixListener = nil
+	}()
+
+	// socket paths are limited to about a hundred bytes, which t.TempDir
+	// may exceed
+	dir, err := os.MkdirTemp("", "gofail")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "gofail.sock")
+
+	// a socket left behind is removed, but a file is not
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file"), nil, 0o600))
+	assert.Error(t, serve("unix://"+filepath.Join(dir, "file")))
+
+	NewFailpoint("failpoint")
+	require.NoError(t, Enable("failpoint", "return(1)"))
+	require.NoError(t, serve("unix://"+path))
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+	// the socket is in use now
+	assert.Error(t, serve("unix://"+path))
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://gofail/failpoint")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "return(1)\n", string(body))
+
+	Shutdown()
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestListSeed(t *testing.T) {
+	defer clearGlobalVars()
+	defer SetSeed(Seed())
+
+	NewFailpoint("failpoint")
+	SetSeed(1234)
+	w := httptest.NewRecorder()
+	(&httpHandler{}).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1234", w.Header().Get("Gofail-Seed"))
+	assert.Equal(t, "failpoint=\n", w.Body.String())
+}
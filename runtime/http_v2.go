@@ -0,0 +1,198 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// The /v2/ HTTP API speaks JSON:
+//
+//	GET    /v2/failpoints                 lists the failpoints
+//	PUT    /v2/failpoints                 sets several failpoints, as EnableAll
+//	GET    /v2/failpoints/<name>          describes a failpoint
+//	PUT    /v2/failpoints/<name>          sets a failpoint or pattern
+//	DELETE /v2/failpoints/<name>          disables a failpoint or pattern
+//	POST   /v2/failpoints/<name>/release  releases the paused goroutines
+//	DELETE /v2/failpoints/<name>/count    resets the counts
+//
+// Errors are reported as an apiError.
+
+// apiFailpoints is the listing of the failpoints.
+type apiFailpoints struct {
+	Seed       int64            `json:"seed"`
+	Failpoints []*FailpointInfo `json:"failpoints"`
+}
+
+// apiTerms is the body setting a single failpoint.
+type apiTerms struct {
+	Terms string `json:"terms"`
+}
+
+type apiError struct {
+	Error apiErrorBody `json:"error"`
+}
+
+type apiErrorBody struct {
+	// Code identifies the error, see errorCode.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Errors are the errors of each entry, when setting several failpoints.
+	Errors []string `json:"errors,omitempty"`
+}
+
+func serveV2(w http.ResponseWriter, r *http.Request, path string) {
+	rest, ok := strings.CutPrefix(path, "failpoints")
+	if !ok || (len(rest) > 0 && rest[0] != '/') {
+		writeError(w, http.StatusNotFound, "not_found", errors.New("no such endpoint"))
+		return
+	}
+	name := strings.TrimPrefix(rest, "/")
+
+	switch {
+	case len(name) == 0 && r.Method == "GET":
+		failpointsMu.RLock()
+		names := list()
+		sort.Strings(names)
+		fps := make([]*Failpoint, len(names))
+		for i, name := range names {
+			fps[i] = failpoints[name]
+		}
+		failpointsMu.RUnlock()
+
+		ret := apiFailpoints{Seed: Seed(), Failpoints: make([]*FailpointInfo, len(fps))}
+		for i, fp := range fps {
+			ret.Failpoints[i] = fp.Info()
+		}
+		writeJSON(w, http.StatusOK, ret)
+
+	case len(name) == 0 && r.Method == "PUT":
+		var c Config
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", err)
+			return
+		}
+		fps := make(map[string]string, len(c.Failpoints))
+		for name, e := range c.Failpoints {
+			fps[name] = e.Terms
+		}
+		if err := EnableAll(fps); err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case len(name) == 0:
+		w.Header().Set("Allow", "GET, PUT")
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", errors.New("method not allowed"))
+
+	case r.Method == "POST" && strings.HasSuffix(name, "/release"):
+		if err := Release(strings.TrimSuffix(name, "/release")); err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == "DELETE" && strings.HasSuffix(name, "/count"):
+		if err := ResetCounts(strings.TrimSuffix(name, "/count")); err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == "GET":
+		info, err := Info(name)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, info)
+
+	case r.Method == "PUT":
+		var t apiTerms
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", err)
+			return
+		}
+		enable := Enable
+		if isPattern(name) {
+			enable = EnablePattern
+		}
+		if err := enable(name, t.Terms); err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == "DELETE":
+		disable := Disable
+		if isPattern(name) {
+			disable = DisablePattern
+		}
+		if err := disable(name); err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", errors.New("method not allowed"))
+	}
+}
+
+// writeAPIError reports an error of the runtime with the matching status and
+// code.
+func writeAPIError(w http.ResponseWriter, err error) {
+	status, code := errorCode(err)
+	writeError(w, status, code, err)
+}
+
+// errorCode gives the status and code reporting an error of the runtime.
+func errorCode(err error) (int, string) {
+	switch {
+	case errors.Is(err, ErrNoExist):
+		return http.StatusNotFound, "not_found"
+	case errors.Is(err, ErrDisabled):
+		return http.StatusConflict, "disabled"
+	case errors.Is(err, ErrAmbiguous):
+		return http.StatusBadRequest, "ambiguous"
+	case errors.Is(err, ErrBadPattern):
+		return http.StatusBadRequest, "bad_pattern"
+	default:
+		// bad terms, unknown values, and the errors of several entries
+		return http.StatusBadRequest, "bad_terms"
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code string, err error) {
+	body := apiErrorBody{Code: code, Message: err.Error()}
+	if errs, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range errs.Unwrap() {
+			body.Errors = append(body.Errors, e.Error())
+		}
+	}
+	writeJSON(w, status, apiError{Error: body})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
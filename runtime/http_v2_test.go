@@ -0,0 +1,115 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPV2(t *testing.T) {
+	defer clearGlobalVars()
+	defer SetSeed(Seed())
+	SetSeed(42)
+
+	fp := NewTypedFailpoint("go.etcd.io/gofail/examples.Sync", "string", "go.etcd.io/gofail/examples/sync.go:42")
+	NewFailpoint("failpoint2")
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		(&httpHandler{}).ServeHTTP(w, r)
+		return w
+	}
+
+	w := do("PUT", "/v2/failpoints/Sync", `{"terms": "return(\"abc\")"}`)
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	fp.Acquire()
+
+	w = do("GET", "/v2/failpoints", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var list apiFailpoints
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, int64(42), list.Seed)
+	require.Len(t, list.Failpoints, 2)
+	assert.Equal(t, "failpoint2", list.Failpoints[0].Name)
+	assert.False(t, list.Failpoints[0].Enabled)
+	assert.Contains(t, list.Failpoints[0].Site, "http_v2_test.go:")
+	sync := list.Failpoints[1]
+	assert.Equal(t, &FailpointInfo{
+		Name:        "go.etcd.io/gofail/examples.Sync",
+		Type:        "string",
+		Site:        "go.etcd.io/gofail/examples/sync.go:42",
+		Enabled:     true,
+		Terms:       `return("abc")`,
+		Count:       1,
+		Evaluations: 1,
+	}, sync)
+
+	w = do("GET", "/v2/failpoints/go.etcd.io/gofail/examples.Sync", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var info FailpointInfo
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, 1, info.Count)
+
+	w = do("DELETE", "/v2/failpoints/Sync/count", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = do("DELETE", "/v2/failpoints/Sync", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	_, err := fp.Acquire()
+	assert.ErrorIs(t, err, ErrDisabled)
+
+	w = do("PUT", "/v2/failpoints", `{"failpoints": {"Sync": {"terms": "return(\"def\")"}, "failpoint2": {"terms": "off"}}}`)
+	assert.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	v, err := fp.Acquire()
+	assert.NoError(t, err)
+	assert.Equal(t, "def", v)
+
+	for _, tt := range []struct {
+		method, path, body string
+		status             int
+		apiErr             apiErrorBody
+	}{
+		{"GET", "/v2/failpoints/unknown", "", http.StatusNotFound, apiErrorBody{Code: "not_found", Message: "failpoint: failpoint does not exist"}},
+		{"DELETE", "/v2/failpoints/unknown", "", http.StatusNotFound, apiErrorBody{Code: "not_found", Message: "failpoint: failpoint does not exist"}},
+		{"POST", "/v2/failpoints/Sync/release", "", http.StatusNoContent, apiErrorBody{}},
+		{"DELETE", "/v2/failpoints/Sync", "", http.StatusNoContent, apiErrorBody{}},
+		{"DELETE", "/v2/failpoints/Sync", "", http.StatusConflict, apiErrorBody{Code: "disabled", Message: "failpoint: failpoint is disabled"}},
+		{"PUT", "/v2/failpoints/Sync", `{"terms": "retrun(1)"}`, http.StatusBadRequest, apiErrorBody{Code: "bad_terms", Message: `failpoint: could not parse "retrun(1)" at offset 0: expected an action (break, error, off, panic, pause, print, return, sleep), found "retrun"`}},
+		{"PUT", "/v2/failpoints/Sync", `retrun(1)`, http.StatusBadRequest, apiErrorBody{Code: "bad_request", Message: "invalid character 'r' looking for beginning of value"}},
+		{"PUT", "/v2/failpoints", `{"failpoints": {"a": {"terms": "off"}, "b": {"terms": "off"}}}`, http.StatusNotFound, apiErrorBody{
+			Code:    "not_found",
+			Message: "a: failpoint: failpoint does not exist\nb: failpoint: failpoint does not exist",
+			Errors:  []string{"a: failpoint: failpoint does not exist", "b: failpoint: failpoint does not exist"},
+		}},
+		{"POST", "/v2/failpoints", "", http.StatusMethodNotAllowed, apiErrorBody{Code: "method_not_allowed", Message: "method not allowed"}},
+		{"GET", "/v2/other", "", http.StatusNotFound, apiErrorBody{Code: "not_found", Message: "no such endpoint"}},
+	} {
+		w := do(tt.method, tt.path, tt.body)
+		assert.Equal(t, tt.status, w.Code, tt.path)
+		if tt.apiErr.Code == "" {
+			continue
+		}
+		var apiErr apiError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &apiErr))
+		assert.Equal(t, tt.apiErr, apiErr.Error)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrBadPattern = fmt.Errorf("failpoint: invalid pattern")
+
+	// patterns set by EnablePattern, in the order they were set; they are
+	// protected by failpointsMu
+	patterns []*pattern
+)
+
+// pattern selects failpoints by their full or short name, either with a glob
+// such as "go.etcd.io/etcd/server/wal.*" or "*BeforeCommit", or with a
+// regular expression prefixed by "re:" such as "re:^raft.*Save$".
+type pattern struct {
+	expr  string
+	re    *regexp.Regexp
+	terms string
+}
+
+// isPattern reports whether a failpoint name given to the runtime is a
+// pattern rather than the name of a single failpoint.
+func isPattern(name string) bool {
+	return strings.HasPrefix(name, "re:") || strings.ContainsAny(name, "*?[")
+}
+
+func newPattern(expr, terms string) (*pattern, error) {
+	p := &pattern{expr: expr, terms: terms}
+	if re, ok := strings.CutPrefix(expr, "re:"); ok {
+		var err error
+		if p.re, err = regexp.Compile(re); err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrBadPattern, expr, err)
+		}
+		return p, nil
+	}
+	if _, err := path.Match(expr, ""); err != nil {
+		return nil, fmt.Errorf("%w %q: %v", ErrBadPattern, expr, err)
+	}
+	return p, nil
+}
+
+func (p *pattern) match(name string) bool {
+	for _, n := range []string{name, shortName(name)} {
+		if p.re != nil {
+			if p.re.MatchString(n) {
+				return true
+			}
+		} else if ok, _ := path.Match(p.expr, n); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// EnablePattern sets every failpoint whose full or short name matches the
+// pattern to the given terms, including the failpoints registered later on.
+// The pattern is either a glob, as understood by path.Match, such as
+// "go.etcd.io/etcd/server/wal.*" or "*BeforeCommit", or a regular expression
+// prefixed by "re:". Setting a pattern again replaces its terms, and when
+// several patterns match a failpoint registered later, the last one set wins.
+func EnablePattern(expr, inTerms string) error {
+	p, err := newPattern(expr, inTerms)
+	if err != nil {
+		return err
+	}
+	// check the terms once, rather than for each matching failpoint
+	if _, err := newTerms(expr, inTerms); err != nil {
+		return err
+	}
+
+	failpointsMu.Lock()
+	setPattern(p)
+	names := matching(p)
+	failpointsMu.Unlock()
+
+	for _, name := range names {
+		if err := Enable(name, inTerms); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DisablePattern forgets a pattern set by EnablePattern, and disables every
+// failpoint matching it.
+func DisablePattern(expr string) error {
+	p, err := newPattern(expr, "")
+	if err != nil {
+		return err
+	}
+
+	failpointsMu.Lock()
+	for i := range patterns {
+		if patterns[i].expr == expr {
+			patterns = append(patterns[:i], patterns[i+1:]...)
+			break
+		}
+	}
+	names := matching(p)
+	failpointsMu.Unlock()
+
+	for _, name := range names {
+		// the failpoints which aren't enabled are fine as they are
+		Disable(name)
+	}
+	return nil
+}
+
+// setPattern adds a pattern, or replaces the one with the same expression;
+// failpointsMu must be held.
+func setPattern(p *pattern) {
+	for i := range patterns {
+		if patterns[i].expr == p.expr {
+			patterns = append(patterns[:i], patterns[i+1:]...)
+			break
+		}
+	}
+	patterns = append(patterns, p)
+}
+
+// matching lists the registered failpoints matching a pattern; failpointsMu
+// must be held.
+func matching(p *pattern) []string {
+	var names []string
+	for name := range failpoints {
+		if p.match(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// patternTerms gives the terms of the last pattern set matching a failpoint;
+// failpointsMu must be held.
+func patternTerms(name string) (string, bool) {
+	for i := len(patterns) - 1; i >= 0; i-- {
+		if patterns[i].match(name) {
+			return patterns[i].terms, true
+		}
+	}
+	return "", false
+}
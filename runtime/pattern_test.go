@@ -0,0 +1,90 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatternMatch(t *testing.T) {
+	tests := []struct {
+		expr   string
+		name   string
+		wmatch bool
+	}{
+		{"go.etcd.io/raft.*", "go.etcd.io/raft.BeforeSave", true},
+		{"go.etcd.io/raft.*", "go.etcd.io/etcd/server/wal.Sync", false},
+		{"*BeforeCommit", "go.etcd.io/etcd/server/storage.raftBeforeCommit", true},
+		{"*BeforeCommit", "BeforeCommitHook", false},
+		{"Sync?", "go.etcd.io/etcd/server/wal.Sync2", true},
+		{"re:^raft", "go.etcd.io/etcd/server.raftBeforeSave", true},
+		{"re:Save$", "go.etcd.io/etcd/server.raftBeforeSave", true},
+		{"re:^go\\.etcd\\.io/etcd/", "go.etcd.io/raft.BeforeSave", false},
+	}
+	for _, tt := range tests {
+		p, err := newPattern(tt.expr, "off")
+		assert.Nil(t, err, tt.expr)
+		assert.Equal(t, tt.wmatch, p.match(tt.name), "%s matching %s", tt.expr, tt.name)
+	}
+
+	for _, expr := range []string{"[a-", "re:(a"} {
+		_, err := newPattern(expr, "off")
+		assert.ErrorIs(t, err, ErrBadPattern, expr)
+	}
+}
+
+func TestEnablePattern(t *testing.T) {
+	defer clearGlobalVars()
+
+	save := NewFailpoint("go.etcd.io/raft.BeforeSave")
+	commit := NewFailpoint("go.etcd.io/raft.BeforeCommit")
+	sync := NewFailpoint("go.etcd.io/etcd/server/wal.Sync")
+
+	assert.ErrorIs(t, EnablePattern("go.etcd.io/raft.*", "retrun"), ErrBadParse)
+	assert.Nil(t, EnablePattern("go.etcd.io/raft.*", "return(1)"))
+	for _, fp := range []*Failpoint{save, commit} {
+		v, err := fp.Acquire()
+		assert.Nil(t, err)
+		assert.Equal(t, 1, v)
+	}
+	_, err := sync.Acquire()
+	assert.ErrorIs(t, err, ErrDisabled)
+
+	// patterns apply to the failpoints registered later on, the last one
+	// set winning
+	assert.Nil(t, EnablePattern("*Apply", "return(2)"))
+	assert.Nil(t, EnablePattern("re:^go\\.etcd\\.io/raft\\.", "return(3)"))
+	apply := NewFailpoint("go.etcd.io/raft.BeforeApply")
+	v, err := apply.Acquire()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, v)
+
+	// a name from GOFAIL_FAILPOINTS takes precedence over patterns
+	envTerms = map[string]string{"AfterApply": "return(4)"}
+	v, err = NewFailpoint("go.etcd.io/raft.AfterApply").Acquire()
+	assert.Nil(t, err)
+	assert.Equal(t, 4, v)
+
+	assert.Nil(t, DisablePattern("re:^go\\.etcd\\.io/raft\\."))
+	for _, fp := range []*Failpoint{save, commit, apply} {
+		_, err := fp.Acquire()
+		assert.ErrorIs(t, err, ErrDisabled)
+	}
+	v, err = NewFailpoint("go.etcd.io/etcd/server.Apply").Acquire()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, v)
+}
@@ -15,21 +15,37 @@
 package runtime
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
+
+	fpterms "go.etcd.io/gofail/terms"
 )
 
 var (
 	ErrNoExist  = fmt.Errorf("failpoint: failpoint does not exist")
 	ErrDisabled = fmt.Errorf("failpoint: failpoint is disabled")
+	// ErrAmbiguous is returned when a short name refers to failpoints of
+	// several packages.
+	ErrAmbiguous = fmt.Errorf("failpoint: failpoint name is ambiguous")
 
+	// failpoints are indexed by their full name, e.g. "go.etcd.io/etcd/server/wal.Sync"
 	failpoints map[string]*Failpoint
+	// shortNames maps the short names of failpoints, e.g. "Sync", to their
+	// full names
+	shortNames map[string][]string
 	// failpointsMu protects the failpoints map, preventing concurrent
 	// accesses during commands such as Enabling and Disabling
 	failpointsMu sync.RWMutex
 
+	// envTerms are the terms of GOFAIL_FAILPOINTS and of the configuration
+	// file by name, applied to the failpoints as they are registered; it is
+	// protected by failpointsMu too
 	envTerms map[string]string
 
 	// panicMu (panic mutex) ensures that the action of panic failpoints
@@ -37,18 +53,59 @@ var (
 	// avoiding the possibility that the server runtime panics during processing
 	// requests
 	panicMu sync.Mutex
+
+	// shutdown is closed by Shutdown to wake up the goroutines blocked by
+	// sleep and pause actions
+	shutdown     = make(chan struct{})
+	shutdownOnce sync.Once
 )
 
 func init() {
 	failpoints = make(map[string]*Failpoint)
+	shortNames = make(map[string][]string)
 	envTerms = make(map[string]string)
-	if s := os.Getenv("GOFAIL_FAILPOINTS"); len(s) > 0 {
-		fpMap, err := parseFailpoints(s)
+	if s := os.Getenv("GOFAIL_SEED"); len(s) > 0 {
+		v, err := strconv.ParseInt(s, 10, 64)
 		if err != nil {
+			fmt.Printf("fail to parse GOFAIL_SEED: %v\n", err)
+			os.Exit(1)
+		}
+		seed = v
+	}
+	if s := os.Getenv("GOFAIL_FAILPOINTS"); len(s) > 0 {
+		if err := loadFailpoints(s); err != nil {
 			fmt.Printf("fail to parse failpoint: %v\n", err)
 			os.Exit(1)
 		}
-		envTerms = fpMap
+	}
+	if s := os.Getenv("GOFAIL_CONFIG"); len(s) > 0 {
+		if err := loadConfig(s); err != nil {
+			fmt.Printf("fail to load failpoints: %v\n", err)
+			os.Exit(1)
+		}
+		interval := time.Second
+		if s := os.Getenv("GOFAIL_CONFIG_INTERVAL"); len(s) > 0 {
+			d, err := time.ParseDuration(s)
+			if err != nil || d <= 0 {
+				fmt.Printf("fail to parse GOFAIL_CONFIG_INTERVAL: %q\n", s)
+				os.Exit(1)
+			}
+			interval = d
+		}
+		go watchConfig(interval)
+	}
+	if s := os.Getenv("GOFAIL_SIGNALS"); len(s) > 0 {
+		on, err := strconv.ParseBool(s)
+		if err != nil {
+			fmt.Printf("fail to parse GOFAIL_SIGNALS: %v\n", err)
+			os.Exit(1)
+		}
+		if on {
+			if err := handleSignals(); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
 	}
 	if s := os.Getenv("GOFAIL_HTTP"); len(s) > 0 {
 		if err := serve(s); err != nil {
@@ -58,68 +115,350 @@ func init() {
 	}
 }
 
-func parseFailpoints(fps string) (map[string]string, error) {
+func parseFailpoints(fps string) ([]fpterms.FailpointTerms, error) {
 	// The format is <FAILPOINT>=<TERMS>[;<FAILPOINT>=<TERMS>]*
-	fpMap := map[string]string{}
+	return fpterms.ParseFailpointList(fps)
+}
 
-	for _, fp := range strings.Split(fps, ";") {
-		if len(fp) == 0 {
+// loadFailpoints applies GOFAIL_FAILPOINTS at startup, before any failpoint
+// is registered. The terms of the names are kept for register, and the
+// patterns are set in the order of the list, so that the last one matching a
+// failpoint applies.
+func loadFailpoints(s string) error {
+	fps, err := parseFailpoints(s)
+	if err != nil {
+		return err
+	}
+	failpointsMu.Lock()
+	defer failpointsMu.Unlock()
+	for _, fp := range fps {
+		if !isPattern(fp.Name) {
+			envTerms[fp.Name] = fp.Terms
 			continue
 		}
-		fpTerm := strings.Split(fp, "=")
-		if len(fpTerm) != 2 {
-			err := fmt.Errorf("bad failpoint %q", fp)
-			return nil, err
+		p, err := newPattern(fp.Name, fp.Terms)
+		if err != nil {
+			return err
 		}
-		fpMap[fpTerm[0]] = fpTerm[1]
+		setPattern(p)
 	}
-	return fpMap, nil
+	return nil
 }
 
 // Enable sets a failpoint to a given failpoint description.
+//
+// Like the other functions taking a failpoint name, it accepts the full name
+// of the failpoint, made of its package import path and its name, such as
+// "go.etcd.io/etcd/server/wal.Sync", or just its short name, "Sync", when no
+// other package has a failpoint with the same name. An ambiguous short name
+// gives an error matching ErrAmbiguous.
 func Enable(name, inTerms string) error {
 	failpointsMu.RLock()
-	fp := failpoints[name]
+	fp, err := lookup(name)
 	failpointsMu.RUnlock()
-	if fp == nil {
-		return ErrNoExist
+	if err != nil {
+		return err
 	}
 
-	t, err := newTerms(name, inTerms)
+	t, err := newTerms(fp.name, inTerms)
 	if err != nil {
-		fmt.Printf("failed to enable \"%s=%s\" (%v)\n", name, inTerms, err)
 		return err
 	}
 
 	fp.SetTerm(t)
+	notify(Event{Type: EventEnable, Name: fp.name, Term: inTerms})
+
+	return nil
+}
+
+// EnableAll sets failpoints to the given terms, by name or pattern as with
+// EnablePattern, all at once: every entry is checked first, and if any is
+// invalid, none is applied and the error lists every invalid entry. Exact
+// names take precedence over the patterns matching them, and patterns are
+// set in the order of their names, so that of two patterns matching a
+// failpoint, the greater one applies.
+func EnableAll(fps map[string]string) error {
+	names := make([]string, 0, len(fps))
+	for name := range fps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]fpterms.FailpointTerms, len(names))
+	for i, name := range names {
+		list[i] = fpterms.FailpointTerms{Name: name, Terms: fps[name]}
+	}
+	return update(list)
+}
+
+// update enables failpoints and patterns as EnableAll does, as a single
+// change: nothing is applied if any entry is invalid. The entries are applied
+// in order, so that the last one setting a failpoint wins, except that exact
+// names still take precedence over the patterns.
+func update(fps []fpterms.FailpointTerms) error {
+	return apply(fps, nil, false)
+}
+
+// updateEnv is like update, for the entries of GOFAIL_FAILPOINTS and of the
+// configuration file: they are kept in envTerms for the failpoints registered
+// later on, and their names resolve as in register, so that they apply to the
+// failpoints which aren't registered yet, and a short name applies to the
+// failpoints of every package having it. The names and patterns to disable
+// are removed from envTerms and the patterns, and the failpoints they applied
+// to get the terms register would give them now, or are disabled.
+func updateEnv(fps []fpterms.FailpointTerms, disable []string) error {
+	return apply(fps, disable, true)
+}
+
+func apply(fps []fpterms.FailpointTerms, disable []string, env bool) error {
+	fps = append([]fpterms.FailpointTerms(nil), fps...)
+	// patterns first, so that exact names override them
+	sort.SliceStable(fps, func(i, j int) bool {
+		return isPattern(fps[i].Name) && !isPattern(fps[j].Name)
+	})
+
+	failpointsMu.Lock()
+	// the entries of envTerms once applied, kept aside until every entry is
+	// known to be valid
+	var newEnv map[string]string
+	if env {
+		newEnv = make(map[string]string, len(envTerms)+len(fps))
+		for name, t := range envTerms {
+			newEnv[name] = t
+		}
+		for _, name := range disable {
+			if !isPattern(name) {
+				delete(newEnv, name)
+			}
+		}
+		for _, e := range fps {
+			if !isPattern(e.Name) {
+				newEnv[e.Name] = e.Terms
+			}
+		}
+	}
+
+	var errs []error
+	var ps []*pattern
+	enabled := make(map[*Failpoint]string)
+	for _, e := range fps {
+		name, inTerms := e.Name, e.Terms
+		if isPattern(name) {
+			p, err := newPattern(name, inTerms)
+			if err == nil {
+				_, err = newTerms(name, inTerms)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				continue
+			}
+			ps = append(ps, p)
+			for _, n := range matching(p) {
+				enabled[failpoints[n]] = inTerms
+			}
+			continue
+		}
+		if env {
+			if _, err := newTerms(name, inTerms); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				continue
+			}
+			for _, fp := range resolve(name, newEnv) {
+				enabled[fp] = inTerms
+			}
+			continue
+		}
+		fp, err := lookup(name)
+		if err == nil {
+			_, err = newTerms(fp.name, inTerms)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		enabled[fp] = inTerms
+	}
+	if len(errs) > 0 {
+		failpointsMu.Unlock()
+		return errors.Join(errs...)
+	}
+
+	var events []Event
+	var disabled []*Failpoint
+	for _, name := range disable {
+		if !isPattern(name) {
+			disabled = append(disabled, resolve(name, newEnv)...)
+			continue
+		}
+		for i := range patterns {
+			if patterns[i].expr == name {
+				for _, n := range matching(patterns[i]) {
+					disabled = append(disabled, failpoints[n])
+				}
+				patterns = append(patterns[:i], patterns[i+1:]...)
+				break
+			}
+		}
+	}
+	if env {
+		envTerms = newEnv
+	}
+	for _, fp := range disabled {
+		if _, ok := enabled[fp]; ok {
+			continue
+		}
+		// another entry may still apply, as when it is registered
+		if t, ok := registerTerms(fp.name); ok {
+			enabled[fp] = t
+			continue
+		}
+		if err := fp.ClearTerm(); err == nil {
+			events = append(events, Event{Type: EventDisable, Name: fp.name})
+		}
+	}
+	for fp, inTerms := range enabled {
+		// the terms were checked above, creating them again gives each
+		// failpoint its own state
+		t, _ := newTerms(fp.name, inTerms)
+		fp.SetTerm(t)
+		events = append(events, Event{Type: EventEnable, Name: fp.name, Term: inTerms})
+	}
+	for _, p := range ps {
+		setPattern(p)
+	}
+	failpointsMu.Unlock()
 
+	for _, e := range events {
+		notify(e)
+	}
 	return nil
 }
 
-// Disable stops a failpoint from firing.
+// resolve gives the registered failpoints an entry of GOFAIL_FAILPOINTS or of
+// the configuration file names, as in register: the failpoint of that full
+// name, and the failpoints of every package having that short name, but for
+// those having an entry of their own in env; failpointsMu must be held.
+func resolve(name string, env map[string]string) []*Failpoint {
+	var fps []*Failpoint
+	if fp, ok := failpoints[name]; ok {
+		fps = append(fps, fp)
+	}
+	for _, full := range shortNames[name] {
+		if _, ok := env[full]; !ok {
+			fps = append(fps, failpoints[full])
+		}
+	}
+	return fps
+}
+
+// Disable stops a failpoint from firing, and wakes up the goroutines paused
+// at it.
 func Disable(name string) error {
 	failpointsMu.RLock()
-	fp := failpoints[name]
+	fp, err := lookup(name)
 	failpointsMu.RUnlock()
-	if fp == nil {
-		return ErrNoExist
+	if err != nil {
+		return err
 	}
 
-	return fp.ClearTerm()
+	if err := fp.ClearTerm(); err != nil {
+		return err
+	}
+	notify(Event{Type: EventDisable, Name: fp.name})
+	return nil
+}
+
+// Release wakes up the goroutines paused at a failpoint by a pause action.
+// Goroutines reaching the failpoint afterwards are paused again until the
+// next Release, or until the failpoint is disabled.
+func Release(name string) error {
+	failpointsMu.RLock()
+	fp, err := lookup(name)
+	failpointsMu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	return fp.Release()
+}
+
+// Paused gives the number of goroutines currently paused at a failpoint.
+func Paused(name string) (int, error) {
+	failpointsMu.RLock()
+	fp, err := lookup(name)
+	failpointsMu.RUnlock()
+	if err != nil {
+		return 0, err
+	}
+
+	return fp.Paused()
+}
+
+// Shutdown wakes up every goroutine sleeping or paused at a failpoint, and
+// makes sleep and pause actions return immediately from then on, so that the
+// process can exit cleanly. When the HTTP endpoint is served on a Unix domain
+// socket, it is closed and the socket file is removed; a process exiting
+// without calling Shutdown leaves the socket file behind.
+func Shutdown() {
+	shutdownOnce.Do(func() {
+		close(shutdown)
+		unixListenerMu.Lock()
+		defer unixListenerMu.Unlock()
+		if unixListener != nil {
+			unixListener.Close()
+		}
+	})
 }
 
 // Status gives the current setting and execution count for the failpoint
 func Status(failpath string) (string, int, error) {
 	failpointsMu.RLock()
-	fp := failpoints[failpath]
+	fp, err := lookup(failpath)
 	failpointsMu.RUnlock()
-	if fp == nil {
-		return "", 0, ErrNoExist
+	if err != nil {
+		return "", 0, err
 	}
 
 	return fp.Status()
 }
 
+// Stats gives the statistics of a failpoint: how many times it was reached,
+// how many times each of its terms was executed and when.
+func Stats(name string) (*FailpointStats, error) {
+	failpointsMu.RLock()
+	fp, err := lookup(name)
+	failpointsMu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return fp.Stats()
+}
+
+// ResetCounts zeroes the counts of a failpoint, as given by Status and Stats,
+// without enabling it again.
+func ResetCounts(name string) error {
+	failpointsMu.RLock()
+	fp, err := lookup(name)
+	failpointsMu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	return fp.ResetCounts()
+}
+
+// Info describes a failpoint, whether it is enabled or not.
+func Info(name string) (*FailpointInfo, error) {
+	failpointsMu.RLock()
+	fp, err := lookup(name)
+	failpointsMu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return fp.Info(), nil
+}
+
 func List() []string {
 	failpointsMu.Lock()
 	defer failpointsMu.Unlock()
@@ -134,18 +473,64 @@ func list() []string {
 	return ret
 }
 
-func register(name string) *Failpoint {
+func register(name, varType, site string) *Failpoint {
 	failpointsMu.Lock()
 	if _, ok := failpoints[name]; ok {
 		failpointsMu.Unlock()
 		panic(fmt.Sprintf("failpoint name %s is already registered.", name))
 	}
 
-	fp := &Failpoint{}
+	fp := &Failpoint{name: name, varType: varType, site: site}
 	failpoints[name] = fp
+	short := shortName(name)
+	if short != name {
+		shortNames[short] = append(shortNames[short], name)
+	}
+	t, ok := registerTerms(name)
 	failpointsMu.Unlock()
-	if t, ok := envTerms[name]; ok {
-		Enable(name, t)
+	if ok {
+		if err := Enable(name, t); err != nil {
+			fmt.Printf("failed to enable \"%s=%s\" (%v)\n", name, t, err)
+		}
 	}
 	return fp
 }
+
+// registerTerms gives the terms a failpoint gets as it is registered, if
+// any; failpointsMu must be held. GOFAIL_FAILPOINTS and the configuration file
+// may give either name; the short one applies to the failpoints of every
+// package having that name. Names take precedence over patterns.
+func registerTerms(name string) (string, bool) {
+	if t, ok := envTerms[name]; ok {
+		return t, true
+	}
+	if t, ok := envTerms[shortName(name)]; ok {
+		return t, true
+	}
+	return patternTerms(name)
+}
+
+// lookup finds a failpoint by its full name or, when it is unique, by its
+// short name; failpointsMu must be held.
+func lookup(name string) (*Failpoint, error) {
+	if fp, ok := failpoints[name]; ok {
+		return fp, nil
+	}
+	switch names := shortNames[name]; len(names) {
+	case 0:
+		return nil, ErrNoExist
+	case 1:
+		return failpoints[names[0]], nil
+	default:
+		sorted := append([]string(nil), names...)
+		sort.Strings(sorted)
+		return nil, fmt.Errorf("%w: %q may be any of %s", ErrAmbiguous, name, strings.Join(sorted, ", "))
+	}
+}
+
+// shortName strips the package import path from a failpoint name, e.g.
+// "go.etcd.io/etcd/server/wal.Sync" becomes "Sync". Failpoint names are Go
+// identifiers, so they are whatever follows the last dot.
+func shortName(name string) string {
+	return name[strings.LastIndexByte(name, '.')+1:]
+}
@@ -14,106 +14,184 @@
 package runtime
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	fpterms "go.etcd.io/gofail/terms"
 )
 
 func TestParseFailpoints(t *testing.T) {
 	testCases := []struct {
-		name           string
-		fps            string
-		expectErr      bool
-		expectedFpsMap map[string]string
+		name        string
+		fps         string
+		expectErr   bool
+		expectedFps []fpterms.FailpointTerms
 	}{
 		{
-			name:           "only one valid failpoint",
-			fps:            "failpoint1=print",
-			expectErr:      false,
-			expectedFpsMap: map[string]string{"failpoint1": "print"},
+			name:        "only one valid failpoint",
+			fps:         "failpoint1=print",
+			expectErr:   false,
+			expectedFps: []fpterms.FailpointTerms{{Name: "failpoint1", Terms: "print"}},
 		},
 		{
-			name:           "only one invalid failpoint",
-			fps:            "failpoint1",
-			expectErr:      true,
-			expectedFpsMap: nil,
+			name:        "only one invalid failpoint",
+			fps:         "failpoint1",
+			expectErr:   true,
+			expectedFps: nil,
 		},
 		{
-			name:           "multiple valid failpoints",
-			fps:            "failpoint1=print;failpoint2=sleep(10)",
-			expectErr:      false,
-			expectedFpsMap: map[string]string{"failpoint1": "print", "failpoint2": "sleep(10)"},
+			name:        "multiple valid failpoints",
+			fps:         "failpoint1=print;failpoint2=sleep(10)",
+			expectErr:   false,
+			expectedFps: []fpterms.FailpointTerms{{Name: "failpoint1", Terms: "print"}, {Name: "failpoint2", Terms: "sleep(10)"}},
 		},
 		{
-			name:           "multiple invalid failpoints",
-			fps:            "failpoint1=print_failpoint2=sleep(10)",
-			expectErr:      true,
-			expectedFpsMap: nil,
+			name:        "multiple invalid failpoints",
+			fps:         "failpoint1=print_failpoint2=sleep(10)",
+			expectErr:   true,
+			expectedFps: nil,
 		},
 		{
-			name:           "partial valid failpoints",
-			fps:            "failpoint1=print;failpoint2",
-			expectErr:      true,
-			expectedFpsMap: nil,
+			name:        "partial valid failpoints",
+			fps:         "failpoint1=print;failpoint2",
+			expectErr:   true,
+			expectedFps: nil,
 		},
 		{
-			name:           "empty failpoints",
-			fps:            "",
-			expectErr:      false,
-			expectedFpsMap: map[string]string{},
+			name:        "empty failpoints",
+			fps:         "",
+			expectErr:   false,
+			expectedFps: nil,
 		},
 		{
-			name:           "one empty failpoint at the head",
-			fps:            ";failpoint1=print;failpoint2=sleep(10)",
-			expectErr:      false,
-			expectedFpsMap: map[string]string{"failpoint1": "print", "failpoint2": "sleep(10)"},
+			name:        "one empty failpoint at the head",
+			fps:         ";failpoint1=print;failpoint2=sleep(10)",
+			expectErr:   false,
+			expectedFps: []fpterms.FailpointTerms{{Name: "failpoint1", Terms: "print"}, {Name: "failpoint2", Terms: "sleep(10)"}},
 		},
 		{
-			name:           "multiple empty failpoints at the head",
-			fps:            ";;failpoint1=print;failpoint2=sleep(10)",
-			expectErr:      false,
-			expectedFpsMap: map[string]string{"failpoint1": "print", "failpoint2": "sleep(10)"},
+			name:        "multiple empty failpoints at the head",
+			fps:         ";;failpoint1=print;failpoint2=sleep(10)",
+			expectErr:   false,
+			expectedFps: []fpterms.FailpointTerms{{Name: "failpoint1", Terms: "print"}, {Name: "failpoint2", Terms: "sleep(10)"}},
 		},
 		{
-			name:           "one empty failpoint at the tail",
-			fps:            "failpoint1=print;failpoint2=sleep(10);",
-			expectErr:      false,
-			expectedFpsMap: map[string]string{"failpoint1": "print", "failpoint2": "sleep(10)"},
+			name:        "one empty failpoint at the tail",
+			fps:         "failpoint1=print;failpoint2=sleep(10);",
+			expectErr:   false,
+			expectedFps: []fpterms.FailpointTerms{{Name: "failpoint1", Terms: "print"}, {Name: "failpoint2", Terms: "sleep(10)"}},
 		},
 		{
-			name:           "multiple empty failpoints at the tail",
-			fps:            "failpoint1=print;failpoint2=sleep(10);;",
-			expectErr:      false,
-			expectedFpsMap: map[string]string{"failpoint1": "print", "failpoint2": "sleep(10)"},
+			name:        "multiple empty failpoints at the tail",
+			fps:         "failpoint1=print;failpoint2=sleep(10);;",
+			expectErr:   false,
+			expectedFps: []fpterms.FailpointTerms{{Name: "failpoint1", Terms: "print"}, {Name: "failpoint2", Terms: "sleep(10)"}},
 		},
 		{
-			name:           "one empty failpoint in the middle",
-			fps:            "failpoint1=print;;failpoint2=sleep(10)",
-			expectErr:      false,
-			expectedFpsMap: map[string]string{"failpoint1": "print", "failpoint2": "sleep(10)"},
+			name:        "one empty failpoint in the middle",
+			fps:         "failpoint1=print;;failpoint2=sleep(10)",
+			expectErr:   false,
+			expectedFps: []fpterms.FailpointTerms{{Name: "failpoint1", Terms: "print"}, {Name: "failpoint2", Terms: "sleep(10)"}},
 		},
 		{
-			name:           "multiple empty failpoints in the middle",
-			fps:            "failpoint1=print;;;failpoint2=sleep(10)",
-			expectErr:      false,
-			expectedFpsMap: map[string]string{"failpoint1": "print", "failpoint2": "sleep(10)"},
+			name:        "multiple empty failpoints in the middle",
+			fps:         "failpoint1=print;;;failpoint2=sleep(10)",
+			expectErr:   false,
+			expectedFps: []fpterms.FailpointTerms{{Name: "failpoint1", Terms: "print"}, {Name: "failpoint2", Terms: "sleep(10)"}},
 		},
 		{
-			name:           "multiple empty failpoints at different places",
-			fps:            ";failpoint1=print;;failpoint2=sleep(10);",
-			expectErr:      false,
-			expectedFpsMap: map[string]string{"failpoint1": "print", "failpoint2": "sleep(10)"},
+			name:        "multiple empty failpoints at different places",
+			fps:         ";failpoint1=print;;failpoint2=sleep(10);",
+			expectErr:   false,
+			expectedFps: []fpterms.FailpointTerms{{Name: "failpoint1", Terms: "print"}, {Name: "failpoint2", Terms: "sleep(10)"}},
 		},
 	}
 
 	for _, tc := range testCases {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
-			fpsMap, err := parseFailpoints(tc.fps)
+			fps, err := parseFailpoints(tc.fps)
 
-			require.Equal(t, tc.expectErr, err != nil, "Unexpected result, tc.expectErr: %t, err: %v", tc.expectedFpsMap, err)
+			require.Equal(t, tc.expectErr, err != nil, "Unexpected result, tc.expectErr: %t, err: %v", tc.expectedFps, err)
 
-			require.Equal(t, tc.expectedFpsMap, fpsMap, "Unexpected result, expected: %v, got: %v", tc.expectedFpsMap, fpsMap)
+			require.Equal(t, tc.expectedFps, fps, "Unexpected result, expected: %v, got: %v", tc.expectedFps, fps)
 		})
 	}
 }
+
+func TestEnableAll(t *testing.T) {
+	defer clearGlobalVars()
+
+	fp1 := NewFailpoint("go.etcd.io/raft.failpoint1")
+	fp2 := NewFailpoint("go.etcd.io/raft.failpoint2")
+	require.NoError(t, Enable("failpoint1", "return(1)"))
+
+	// nothing is applied when any entry is invalid, and every one is reported
+	err := EnableAll(map[string]string{
+		"failpoint2": "return(2)",
+		"failpoint1": "retrun(2)",
+		"unknown":    "return(2)",
+		"re:(":       "return(2)",
+	})
+	require.Error(t, err)
+	require.ErrorIs(t, err, ErrBadParse)
+	require.ErrorIs(t, err, ErrNoExist)
+	require.ErrorIs(t, err, ErrBadPattern)
+	lines := strings.Split(err.Error(), "\n")
+	require.Len(t, lines, 3, err.Error())
+	require.True(t, strings.HasPrefix(lines[1], "failpoint1: "), err.Error())
+	v, err := fp1.Acquire()
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+	_, err = fp2.Acquire()
+	require.ErrorIs(t, err, ErrDisabled)
+
+	// exact names take precedence over patterns
+	require.NoError(t, EnableAll(map[string]string{
+		"failpoint2":        "return(3)",
+		"go.etcd.io/raft.*": "return(4)",
+	}))
+	v, err = fp1.Acquire()
+	require.NoError(t, err)
+	require.Equal(t, 4, v)
+	v, err = fp2.Acquire()
+	require.NoError(t, err)
+	require.Equal(t, 3, v)
+	v, err = NewFailpoint("go.etcd.io/raft.failpoint3").Acquire()
+	require.NoError(t, err)
+	require.Equal(t, 4, v)
+}
+
+func TestPatternOrder(t *testing.T) {
+	defer clearGlobalVars()
+
+	// the last pattern matching a failpoint applies, in the order given
+	for _, tc := range []struct {
+		fps      string
+		expected int
+	}{
+		{"go.etcd.io/raft.*=return(1);*Save=return(2)", 2},
+		{"*Save=return(2);go.etcd.io/raft.*=return(1)", 1},
+	} {
+		clearGlobalVars()
+		require.NoError(t, loadFailpoints(tc.fps))
+		v, err := NewFailpoint("go.etcd.io/raft.raftSave").Acquire()
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, v, tc.fps)
+
+		// and so does PUT /failpoints
+		clearGlobalVars()
+		fp := NewFailpoint("go.etcd.io/raft.raftSave")
+		r := httptest.NewRequest("PUT", "/failpoints", strings.NewReader(tc.fps))
+		w := httptest.NewRecorder()
+		(&httpHandler{}).ServeHTTP(w, r)
+		require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+		v, err = fp.Acquire()
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, v, tc.fps)
+	}
+}
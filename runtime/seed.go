@@ -0,0 +1,64 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	// seed is the root of the random streams of all failpoints. It is taken
+	// from GOFAIL_SEED if set, and picked at random otherwise.
+	seed = time.Now().UnixNano()
+	// seedMu protects seed
+	seedMu sync.RWMutex
+)
+
+// SetSeed sets the seed from which the random stream of each failpoint is
+// derived, and restarts the streams of the enabled failpoints. A failpoint's
+// stream only depends on the seed and the failpoint name, so running the same
+// program with the same seed makes the same probabilistic decisions.
+func SetSeed(s int64) {
+	seedMu.Lock()
+	seed = s
+	seedMu.Unlock()
+
+	failpointsMu.RLock()
+	defer failpointsMu.RUnlock()
+	for _, fp := range failpoints {
+		fp.mux.RLock()
+		if fp.t != nil {
+			fp.t.reseed()
+		}
+		fp.mux.RUnlock()
+	}
+}
+
+// Seed gives the seed currently in use.
+func Seed() int64 {
+	seedMu.RLock()
+	defer seedMu.RUnlock()
+	return seed
+}
+
+// newRand returns the random stream for the named failpoint.
+func newRand(name string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return rand.New(rand.NewSource(Seed() ^ int64(h.Sum64())))
+}
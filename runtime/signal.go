@@ -0,0 +1,82 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// dump writes every registered failpoint with its terms and counts, and the
+// patterns set, as done on SIGUSR1.
+func dump(w io.Writer) {
+	failpointsMu.RLock()
+	names := list()
+	sort.Strings(names)
+	fps := make([]*Failpoint, len(names))
+	for i, name := range names {
+		fps[i] = failpoints[name]
+	}
+	ps := append([]*pattern(nil), patterns...)
+	failpointsMu.RUnlock()
+
+	fmt.Fprintf(w, "gofail: %d failpoints, seed %d\n", len(fps), Seed())
+	for _, fp := range fps {
+		info := fp.Info()
+		if !info.Enabled {
+			fmt.Fprintf(w, "%s: disabled\n", info.Name)
+			continue
+		}
+		fmt.Fprintf(w, "%s=%s evaluations=%d hits=%d sleeping=%d paused=%d\n",
+			info.Name, info.Terms, info.Evaluations, info.Count, info.Sleeping, info.Paused)
+	}
+	for _, p := range ps {
+		fmt.Fprintf(w, "pattern %s=%s\n", p.expr, p.terms)
+	}
+}
+
+// reload applies GOFAIL_FAILPOINTS and the configuration file again, as done
+// on SIGUSR2, all at once as EnableAll does. The failpoints they set get
+// fresh terms, as when they are registered, so that a process can be brought
+// back to its startup settings; the entries removed from the configuration
+// file since it was last applied are disabled.
+func reload() error {
+	if _, err := parseFailpoints(os.Getenv("GOFAIL_FAILPOINTS")); err != nil {
+		return err
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+	var c *Config
+	var data []byte
+	if configPath != "" {
+		var err error
+		if data, err = os.ReadFile(configPath); err != nil {
+			return fmt.Errorf("%w: %v", ErrBadConfig, err)
+		}
+		if c, err = parseConfig(configPath, data); err != nil {
+			return err
+		}
+	}
+	if err := applyConfig(c, true); err != nil {
+		return err
+	}
+	if configPath != "" {
+		configData = data
+	}
+	return nil
+}
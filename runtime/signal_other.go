@@ -0,0 +1,27 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !unix
+
+package runtime
+
+import (
+	"fmt"
+	goruntime "runtime"
+)
+
+// handleSignals fails, as SIGUSR1 and SIGUSR2 only exist on Unix systems.
+func handleSignals() error {
+	return fmt.Errorf("failpoint: GOFAIL_SIGNALS is not supported on %s", goruntime.GOOS)
+}
@@ -0,0 +1,115 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDump(t *testing.T) {
+	defer clearGlobalVars()
+	defer SetSeed(Seed())
+	SetSeed(42)
+
+	fp1 := NewFailpoint("failpoint1")
+	NewFailpoint("failpoint2")
+	require.NoError(t, Enable("failpoint1", `sleep("1h")`))
+	require.NoError(t, EnablePattern("late*", `return(1)`))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fp1.Acquire()
+	}()
+	var out strings.Builder
+	assert.Eventually(t, func() bool {
+		out.Reset()
+		dump(&out)
+		return strings.Contains(out.String(), "sleeping=1")
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, `gofail: 2 failpoints, seed 42
+failpoint1=sleep("1h") evaluations=1 hits=1 sleeping=1 paused=0
+failpoint2: disabled
+pattern late*=return(1)
+`, out.String())
+
+	require.NoError(t, Disable("failpoint1"))
+	<-done
+}
+
+func TestReload(t *testing.T) {
+	defer clearGlobalVars()
+
+	path := filepath.Join(t.TempDir(), "gofail.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"failpoints": {
+		"failpoint1": {"terms": "return(1)"},
+		"failpoint2": {"terms": "return(2)"}
+	}}`), 0o644))
+	t.Setenv("GOFAIL_FAILPOINTS", `Sync=1*return(3)`)
+	envTerms["Sync"] = `1*return(3)`
+	require.NoError(t, loadConfig(path))
+
+	fp1 := NewFailpoint("failpoint1")
+	fp2 := NewFailpoint("failpoint2")
+	sync1 := NewFailpoint("go.etcd.io/etcd/server/wal.Sync")
+	sync2 := NewFailpoint("go.etcd.io/raft.Sync")
+	for _, fp := range []*Failpoint{sync1, sync2} {
+		assert.Equal(t, 3, mustAcquire(t, fp))
+		_, err := fp.Acquire()
+		assert.ErrorIs(t, err, ErrDisabled)
+	}
+	require.NoError(t, Enable("failpoint1", `return(4)`))
+
+	// the startup settings apply again, with the changes to the file
+	require.NoError(t, os.WriteFile(path, []byte(`{"failpoints": {
+		"failpoint1": {"terms": "return(1)"}
+	}}`), 0o644))
+	require.NoError(t, reload())
+	assert.Equal(t, 1, mustAcquire(t, fp1))
+	_, err := fp2.Acquire()
+	assert.ErrorIs(t, err, ErrDisabled)
+	for _, fp := range []*Failpoint{sync1, sync2} {
+		assert.Equal(t, 3, mustAcquire(t, fp))
+	}
+
+	// the failpoints registered afterwards get the entries of the file as
+	// reloaded, not as loaded at startup
+	require.NoError(t, os.WriteFile(path, []byte(`{"failpoints": {
+		"failpoint1": {"terms": "return(1)"},
+		"lazy": {"terms": "return(5)"}
+	}}`), 0o644))
+	require.NoError(t, reload())
+	changed, err := reloadConfig()
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, 5, mustAcquire(t, NewFailpoint("go.etcd.io/gofail/runtime.lazy")))
+	_, err = NewFailpoint("go.etcd.io/gofail/runtime.failpoint2").Acquire()
+	assert.ErrorIs(t, err, ErrDisabled)
+
+	// nothing is applied if the file is invalid
+	require.NoError(t, Enable("failpoint1", `return(4)`))
+	require.NoError(t, os.WriteFile(path, []byte(`{"failpoints": {
+		"failpoint1": {"terms": "retrun(1)"}
+	}}`), 0o644))
+	assert.ErrorIs(t, reload(), ErrBadConfig)
+	assert.Equal(t, 4, mustAcquire(t, fp1))
+}
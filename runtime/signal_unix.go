@@ -0,0 +1,44 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build unix
+
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// handleSignals dumps the failpoints to stderr on SIGUSR1, and applies
+// GOFAIL_FAILPOINTS and the configuration file again on SIGUSR2.
+func handleSignals() error {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for sig := range c {
+			switch sig {
+			case syscall.SIGUSR1:
+				dump(os.Stderr)
+			case syscall.SIGUSR2:
+				if err := reload(); err != nil {
+					fmt.Fprintf(os.Stderr, "fail to reload failpoints: %v\n", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,226 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"fmt"
+	"time"
+)
+
+// State is the state of all the failpoints, as taken by Snapshot and applied
+// by Restore. It can be serialized to JSON.
+type State struct {
+	// Failpoints are the enabled failpoints by full name; the failpoints
+	// which aren't listed are disabled.
+	Failpoints map[string]*FailpointState `json:"failpoints"`
+	// Patterns are the patterns set by EnablePattern, in order.
+	Patterns []PatternState `json:"patterns,omitempty"`
+}
+
+// FailpointState is the state of an enabled failpoint.
+type FailpointState struct {
+	Terms string `json:"terms"`
+	// Enabled is when the terms were enabled, which time windows are
+	// relative to.
+	Enabled     time.Time `json:"enabled"`
+	Evaluations int       `json:"evaluations"`
+	Hits        int       `json:"hits"`
+	FirstHit    time.Time `json:"firstHit"`
+	LastHit     time.Time `json:"lastHit"`
+	// PerTerm are the states of the terms of the "->" chain.
+	PerTerm []TermState `json:"perTerm"`
+}
+
+// TermState is the state of a term of a failpoint.
+type TermState struct {
+	Hits     int       `json:"hits"`
+	FirstHit time.Time `json:"firstHit"`
+	LastHit  time.Time `json:"lastHit"`
+	// Mods are the states of the modifiers of the term, in order.
+	Mods []ModState `json:"mods"`
+}
+
+// ModState is the state of a term modifier: the executions left to N*, the
+// evaluations seen by after and every, and the last executions allowed by
+// rate. It is empty for the modifiers without state.
+type ModState struct {
+	Count int         `json:"count,omitempty"`
+	Times []time.Time `json:"times,omitempty"`
+	Next  int         `json:"next,omitempty"`
+}
+
+// PatternState is a pattern set by EnablePattern.
+type PatternState struct {
+	Pattern string `json:"pattern"`
+	Terms   string `json:"terms"`
+}
+
+// statefulMod is a modifier whose state changes as the terms are evaluated.
+type statefulMod interface {
+	mod
+	state() ModState
+	setState(ModState)
+}
+
+func (mc *modCount) state() ModState     { return ModState{Count: mc.c} }
+func (mc *modCount) setState(s ModState) { mc.c = s.Count }
+func (ma *modAfter) state() ModState     { return ModState{Count: ma.seen} }
+func (ma *modAfter) setState(s ModState) { ma.seen = s.Count }
+func (me *modEvery) state() ModState     { return ModState{Count: me.seen} }
+func (me *modEvery) setState(s ModState) { me.seen = s.Count }
+
+func (mr *modRate) state() ModState {
+	return ModState{Times: append([]time.Time(nil), mr.times...), Next: mr.next}
+}
+
+func (mr *modRate) setState(s ModState) {
+	mr.times = append([]time.Time(nil), s.Times...)
+	mr.next = s.Next
+	if len(mr.times) > mr.limit || (len(mr.times) > 0 && mr.next >= len(mr.times)) {
+		// not a state of this modifier, start over
+		mr.times, mr.next = nil, 0
+	}
+}
+
+// Snapshot captures the state of all the failpoints: their terms, their
+// counts, and the state of the modifiers of their terms, such as the
+// executions left to N*. The random streams of the failpoints are not part
+// of it; they restart from the seed on Restore.
+func Snapshot() *State {
+	failpointsMu.RLock()
+	defer failpointsMu.RUnlock()
+
+	snap := &State{Failpoints: make(map[string]*FailpointState)}
+	for name, fp := range failpoints {
+		fp.mux.RLock()
+		if fp.t != nil {
+			snap.Failpoints[name] = fp.t.snapshot()
+		}
+		fp.mux.RUnlock()
+	}
+	for _, p := range patterns {
+		snap.Patterns = append(snap.Patterns, PatternState{Pattern: p.expr, Terms: p.terms})
+	}
+	return snap
+}
+
+// Restore applies a snapshot taken by Snapshot: the failpoints it lists are
+// set to the terms and state they had, the others are disabled, and the
+// patterns are replaced. Either the whole snapshot is applied, or nothing is
+// if it is not valid.
+func Restore(snap *State) error {
+	failpointsMu.Lock()
+	enabled := make(map[*Failpoint]*terms)
+	for name, fs := range snap.Failpoints {
+		fp, err := lookup(name)
+		if err != nil {
+			failpointsMu.Unlock()
+			return fmt.Errorf("%w: %s", err, name)
+		}
+		t, err := newTerms(fp.name, fs.Terms)
+		if err != nil {
+			failpointsMu.Unlock()
+			return err
+		}
+		if err := t.restore(fs); err != nil {
+			failpointsMu.Unlock()
+			return fmt.Errorf("failpoint: cannot restore %s: %v", name, err)
+		}
+		enabled[fp] = t
+	}
+	var ps []*pattern
+	for _, p := range snap.Patterns {
+		pt, err := newPattern(p.Pattern, p.Terms)
+		if err != nil {
+			failpointsMu.Unlock()
+			return err
+		}
+		ps = append(ps, pt)
+	}
+
+	var events []Event
+	for _, fp := range failpoints {
+		if t, ok := enabled[fp]; ok {
+			fp.SetTerm(t)
+			events = append(events, Event{Type: EventEnable, Name: fp.name, Term: t.desc})
+		} else if fp.ClearTerm() == nil {
+			events = append(events, Event{Type: EventDisable, Name: fp.name})
+		}
+	}
+	patterns = ps
+	failpointsMu.Unlock()
+
+	for _, e := range events {
+		notify(e)
+	}
+	return nil
+}
+
+func (t *terms) snapshot() *FailpointState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fs := &FailpointState{
+		Terms:       t.desc,
+		Enabled:     t.enabled,
+		Evaluations: t.evals,
+		Hits:        t.counter,
+		FirstHit:    t.firstHit,
+		LastHit:     t.lastHit,
+	}
+	for _, term := range t.chain {
+		ts := TermState{Hits: term.hits, FirstHit: term.firstHit, LastHit: term.lastHit}
+		for _, m := range term.mods.(*modList).l {
+			var s ModState
+			if sm, ok := m.(statefulMod); ok {
+				s = sm.state()
+			}
+			ts.Mods = append(ts.Mods, s)
+		}
+		fs.PerTerm = append(fs.PerTerm, ts)
+	}
+	return fs
+}
+
+// restore sets the state of newly created terms from a snapshot of the same
+// terms. The parts left out of the snapshot keep the state of new terms, so
+// that a state may as well be written by hand.
+func (t *terms) restore(fs *FailpointState) error {
+	if len(fs.PerTerm) > 0 && len(fs.PerTerm) != len(t.chain) {
+		return fmt.Errorf("got the state of %d terms, expected %d", len(fs.PerTerm), len(t.chain))
+	}
+	for i, ts := range fs.PerTerm {
+		mods := t.chain[i].mods.(*modList).l
+		if len(ts.Mods) > 0 && len(ts.Mods) != len(mods) {
+			return fmt.Errorf("got the state of %d modifiers for term %q, expected %d",
+				len(ts.Mods), t.chain[i].desc, len(mods))
+		}
+	}
+
+	if !fs.Enabled.IsZero() {
+		t.enabled = fs.Enabled
+	}
+	t.evals, t.counter = fs.Evaluations, fs.Hits
+	t.firstHit, t.lastHit = fs.FirstHit, fs.LastHit
+	for i, ts := range fs.PerTerm {
+		term := t.chain[i]
+		term.hits, term.firstHit, term.lastHit = ts.Hits, ts.FirstHit, ts.LastHit
+		for j, ms := range ts.Mods {
+			if sm, ok := term.mods.(*modList).l[j].(statefulMod); ok {
+				sm.setState(ms)
+			}
+		}
+	}
+	return nil
+}
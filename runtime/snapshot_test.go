@@ -0,0 +1,98 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	defer clearGlobalVars()
+
+	fp1 := NewFailpoint("failpoint1")
+	fp2 := NewFailpoint("failpoint2")
+	fp3 := NewFailpoint("failpoint3")
+
+	require.NoError(t, Enable("failpoint1", `3*return(1)->every(2)return(2)`))
+	require.NoError(t, Enable("failpoint2", `after(1)rate(1/h)return(3)`))
+	require.NoError(t, EnablePattern("late*", `return(4)`))
+	for i := 0; i < 2; i++ {
+		fp1.Acquire()
+		fp2.Acquire()
+	}
+
+	// the snapshot goes through JSON, as with the HTTP endpoint
+	b, err := json.Marshal(Snapshot())
+	require.NoError(t, err)
+	var snap State
+	require.NoError(t, json.Unmarshal(b, &snap))
+
+	require.NoError(t, Enable("failpoint1", `return(5)`))
+	require.NoError(t, Disable("failpoint2"))
+	require.NoError(t, Enable("failpoint3", `return(6)`))
+	require.NoError(t, DisablePattern("late*"))
+
+	require.NoError(t, Restore(&snap))
+	st, err := Stats("failpoint1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, st.Hits)
+	assert.Equal(t, 1, st.PerTerm[0].Remaining)
+	for _, want := range []int{1, 2, 2} {
+		v, _ := fp1.Acquire()
+		assert.Equal(t, want, v)
+		fp1.Acquire()
+	}
+	// the rate of failpoint2 is used up
+	_, err = fp2.Acquire()
+	assert.ErrorIs(t, err, ErrDisabled)
+	// failpoint3 was disabled when the snapshot was taken
+	_, err = fp3.Acquire()
+	assert.ErrorIs(t, err, ErrDisabled)
+	// but the pattern applies again
+	v, err := NewFailpoint("lateFailpoint").Acquire()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, v)
+}
+
+func TestRestoreIsAtomic(t *testing.T) {
+	defer clearGlobalVars()
+
+	NewFailpoint("failpoint1")
+	NewFailpoint("failpoint2")
+	require.NoError(t, Enable("failpoint1", `return(1)`))
+	before := Snapshot()
+
+	for _, snap := range []*State{
+		{Failpoints: map[string]*FailpointState{"failpoint2": {Terms: `return(2)`}, "unknown": {Terms: `off`}}},
+		{Failpoints: map[string]*FailpointState{"failpoint2": {Terms: `retrun(2)`}}},
+		{Failpoints: map[string]*FailpointState{"failpoint2": {Terms: `return(2)`, PerTerm: make([]TermState, 2)}}},
+		{Failpoints: map[string]*FailpointState{}, Patterns: []PatternState{{Pattern: "[", Terms: "off"}}},
+	} {
+		assert.Error(t, Restore(snap))
+		assert.Equal(t, before, Snapshot())
+	}
+
+	// a state written by hand only needs the terms
+	require.NoError(t, Restore(&State{Failpoints: map[string]*FailpointState{"failpoint2": {Terms: `return(2)`}}}))
+	_, _, err := Status("failpoint1")
+	assert.ErrorIs(t, err, ErrDisabled)
+	s, _, err := Status("failpoint2")
+	assert.NoError(t, err)
+	assert.Equal(t, `return(2)`, s)
+}
@@ -15,20 +15,26 @@
 package runtime
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 	"os"
 	"os/exec"
-	"strings"
 	"sync"
 	"time"
+
+	fpterms "go.etcd.io/gofail/terms"
 )
 
 var (
 	ErrExhausted = fmt.Errorf("failpoint: terms exhausted")
-	ErrBadParse  = fmt.Errorf("failpoint: could not parse terms")
+	ErrBadParse  = fpterms.ErrBadParse
 )
 
+// ParseError describes why a failpoint terms string could not be parsed.
+// It matches ErrBadParse with errors.Is.
+type ParseError = fpterms.ParseError
+
 // terms encodes the state for a failpoint term string (see fail(9) for examples)
 // <fp> :: <term> ( "->" <term> )*
 type terms struct {
@@ -38,31 +44,60 @@ type terms struct {
 	desc string
 	// fpath is the failpoint path for these terms
 	fpath string
+	// enabled is when the terms were created, which time windows are
+	// relative to
+	enabled time.Time
 
 	// mu protects the state of the terms chain
 	mu sync.Mutex
 	// tracks executions count of terms that are actually evaluated
 	counter int
+	// evals is the number of times the terms were evaluated, whether a term
+	// was executed or not
+	evals int
+	// firstHit and lastHit are when a term was first and last executed
+	firstHit, lastHit time.Time
+	// rnd is the random stream of the failpoint, see SetSeed
+	rnd *rand.Rand
+
+	// release is closed by Release to wake up the goroutines blocked in pause
+	release chan struct{}
+	// paused is the number of goroutines currently blocked in pause
+	paused int
+	// sleeping is the number of goroutines currently blocked in sleep
+	sleeping int
+	// done is closed once the terms are cleared or replaced, waking up the
+	// goroutines blocked in sleep or pause
+	done chan struct{}
 }
 
 // term is an executable unit of the failpoint terms chain
 type term struct {
 	desc string
+	// hits is the number of times the term was executed, when it was
+	// first and last, protected by the lock of the parent terms
+	hits              int
+	firstHit, lastHit time.Time
 
 	mods mod
 	act  actFunc
 	val  interface{}
+	// blocks is set for the actions which block or don't return, see
+	// blockingActs
+	blocks bool
 
 	parent *terms
 }
 
+// mod decides whether a term is executed; allow is called with the lock of
+// the terms held.
 type mod interface {
-	allow() bool
+	allow(t *terms) bool
 }
 
 type modCount struct{ c int }
 
-func (mc *modCount) allow() bool {
+func (mc *modCount) allow(_ *terms) bool {
 	if mc.c > 0 {
 		mc.c--
 		return true
@@ -72,13 +107,70 @@ func (mc *modCount) allow() bool {
 
 type modProb struct{ p float64 }
 
-func (mp *modProb) allow() bool { return rand.Float64() <= mp.p }
+func (mp *modProb) allow(t *terms) bool { return t.rnd.Float64() <= mp.p }
+
+// modAfter skips the first n evaluations.
+type modAfter struct{ n, seen int }
+
+func (ma *modAfter) allow(_ *terms) bool {
+	if ma.seen < ma.n {
+		ma.seen++
+		return false
+	}
+	return true
+}
+
+// modEvery allows every n-th evaluation.
+type modEvery struct{ n, seen int }
+
+func (me *modEvery) allow(_ *terms) bool {
+	me.seen++
+	if me.seen == me.n {
+		me.seen = 0
+		return true
+	}
+	return false
+}
+
+// modRate allows at most limit evaluations in any period of time per.
+type modRate struct {
+	limit int
+	per   time.Duration
+	// times of the last limit allowed evaluations, as a ring buffer
+	times []time.Time
+	next  int
+}
+
+func (mr *modRate) allow(_ *terms) bool {
+	now := time.Now()
+	if len(mr.times) < mr.limit {
+		mr.times = append(mr.times, now)
+		return true
+	}
+	if now.Sub(mr.times[mr.next]) < mr.per {
+		return false
+	}
+	mr.times[mr.next] = now
+	mr.next = (mr.next + 1) % mr.limit
+	return true
+}
+
+// modDelay arms the term once d has elapsed since the terms were enabled.
+type modDelay struct{ d time.Duration }
+
+func (md *modDelay) allow(t *terms) bool { return time.Since(t.enabled) >= md.d }
+
+// modFor keeps the term active for d, starting once the delays preceding it
+// in the term have elapsed.
+type modFor struct{ start, d time.Duration }
+
+func (mf *modFor) allow(t *terms) bool { return time.Since(t.enabled) < mf.start+mf.d }
 
 type modList struct{ l []mod }
 
-func (ml *modList) allow() bool {
+func (ml *modList) allow(t *terms) bool {
 	for _, m := range ml.l {
-		if !m.allow() {
+		if !m.allow(t) {
 			return false
 		}
 	}
@@ -86,197 +178,205 @@ func (ml *modList) allow() bool {
 }
 
 func newTerms(fpath, desc string) (*terms, error) {
-	chain := parse(desc)
-	if len(chain) == 0 {
-		return nil, ErrBadParse
+	ast, err := fpterms.Parse(desc)
+	if err != nil {
+		return nil, err
+	}
+	t := &terms{
+		desc:    desc,
+		fpath:   fpath,
+		enabled: time.Now(),
+		rnd:     newRand(fpath),
+		release: make(chan struct{}),
+		done:    make(chan struct{}),
 	}
-	t := &terms{chain: chain, desc: desc, fpath: fpath}
-	for _, c := range chain {
+	for _, a := range ast.Chain {
+		c, err := compileTerm(a)
+		if err != nil {
+			return nil, err
+		}
 		c.parent = t
+		t.chain = append(t.chain, c)
 	}
 	return t, nil
 }
 
-func (t *terms) String() string { return t.desc }
-
-func (t *terms) eval() interface{} {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	for _, term := range t.chain {
-		if term.mods.allow() {
-			t.counter++
-			return term.do()
+// compileTerm turns a parsed term into an executable one, resolving the
+// references to registered values.
+func compileTerm(a *fpterms.Term) (*term, error) {
+	act, ok := actMap[a.Action]
+	if !ok {
+		return nil, fmt.Errorf("failpoint: unsupported action %q", a.Action)
+	}
+	c := &term{desc: a.String(), act: act, val: a.Value, blocks: blockingActs[a.Action]}
+	switch v := a.Value.(type) {
+	case fpterms.Ref:
+		val, err := lookupValue(string(v))
+		if err != nil {
+			return nil, err
 		}
+		c.val = val
+	case fpterms.Range:
+		c.val = delayRange{v.Min, v.Max}
+	case fpterms.Exp:
+		c.val = delayExp{v.Mean}
+	case fpterms.Normal:
+		c.val = delayNormal{v.Mean, v.Stddev}
 	}
-	return nil
-}
 
-// split terms from a -> b -> ... into [a, b, ...]
-func parse(desc string) (chain []*term) {
-	origDesc := desc
-	for len(desc) != 0 {
-		t := parseTerm(desc)
-		if t == nil {
-			fmt.Printf("failed to parse %q past %q\n", origDesc, desc)
-			return nil
-		}
-		desc = desc[len(t.desc):]
-		chain = append(chain, t)
-		if len(desc) >= 2 {
-			if !strings.HasPrefix(desc, "->") {
-				fmt.Printf("failed to parse %q past %q, expected \"->\"\n", origDesc, desc)
-				return nil
-			}
-			desc = desc[2:]
+	var mods []mod
+	// a window given by for starts after the delays preceding it
+	var start time.Duration
+	for _, m := range a.Mods {
+		switch m := m.(type) {
+		case fpterms.Prob:
+			mods = append(mods, &modProb{m.Percent / 100.0})
+		case fpterms.Count:
+			mods = append(mods, &modCount{m.N})
+		case fpterms.After:
+			mods = append(mods, &modAfter{n: m.N})
+		case fpterms.Every:
+			mods = append(mods, &modEvery{n: m.N})
+		case fpterms.Rate:
+			mods = append(mods, &modRate{limit: m.Limit, per: m.Per})
+		case fpterms.Delay:
+			start += m.D
+			mods = append(mods, &modDelay{d: m.D})
+		case fpterms.For:
+			mods = append(mods, &modFor{start: start, d: m.D})
+		default:
+			return nil, fmt.Errorf("failpoint: unsupported modifier %v", m)
 		}
 	}
-	return chain
+	c.mods = &modList{mods}
+	return c, nil
 }
 
-// <term> :: <mod> <act> [ "(" <val> ")" ]
-func parseTerm(desc string) *term {
-	t := &term{}
-	modStr, mods := parseMod(desc)
-	t.mods = &modList{mods}
-	actStr, act := parseAct(desc[len(modStr):])
-	t.act = act
-	valStr, val := parseVal(desc[len(modStr)+len(actStr):])
-	t.val = val
-	t.desc = desc[:len(modStr)+len(actStr)+len(valStr)]
-	if len(t.desc) == 0 {
-		return nil
-	}
-	return t
-}
+func (t *terms) String() string { return t.desc }
 
-// <mod> :: ((<float> "%")|(<int> "*" ))*
-func parseMod(desc string) (ret string, mods []mod) {
-	for {
-		s, v := parseIntFloat(desc)
-		if len(s) == 0 {
-			break
-		}
-		if len(s) == len(desc) {
-			return "", nil
-		}
-		switch v := v.(type) {
-		case float64:
-			if desc[len(s)] != '%' {
-				return "", nil
+func (t *terms) eval() interface{} {
+	t.mu.Lock()
+	t.evals++
+	var matched *term
+	for _, term := range t.chain {
+		if term.mods.allow(t) {
+			now := time.Now()
+			t.counter++
+			if t.firstHit.IsZero() {
+				t.firstHit = now
 			}
-			ret = ret + desc[:len(s)+1]
-			mods = append(mods, &modProb{v / 100.0})
-			desc = desc[len(s)+1:]
-		case int:
-			if desc[len(s)] != '*' {
-				return "", nil
+			t.lastHit = now
+			term.hits++
+			if term.firstHit.IsZero() {
+				term.firstHit = now
 			}
-			ret = ret + desc[:len(s)+1]
-			mods = append(mods, &modCount{v})
-			desc = desc[len(s)+1:]
-		default:
-			panic("???")
-		}
-	}
-	return ret, mods
-}
-
-// parseIntFloat parses an int or float from a string and returns the string
-// it parsed it from (unlike scanf).
-func parseIntFloat(desc string) (string, interface{}) {
-	// parse for ints
-	i := 0
-	for i < len(desc) {
-		if desc[i] < '0' || desc[i] > '9' {
+			term.lastHit = now
+			matched = term
 			break
 		}
-		i++
-	}
-	if i == 0 {
-		return "", nil
-	}
-
-	intVal := int(0)
-	_, err := fmt.Sscanf(desc[:i], "%d", &intVal)
-	if err != nil {
-		return "", nil
 	}
-	if len(desc) == i {
-		return desc[:i], intVal
+	t.mu.Unlock()
+	if matched == nil {
+		return nil
 	}
-	if desc[i] != '.' {
-		return desc[:i], intVal
+	// the action runs without holding the lock, so blocking actions such as
+	// sleep and pause don't hold up other goroutines evaluating the terms
+	if matched.blocks {
+		notify(Event{Type: EventTrigger, Name: t.fpath, Term: matched.desc, Value: matched.val})
+		return matched.do()
 	}
+	v := matched.do()
+	notify(Event{Type: EventTrigger, Name: t.fpath, Term: matched.desc, Value: v})
+	return v
+}
 
-	// parse for floats
-	i++
-	if i == len(desc) {
-		return desc[:i], float64(intVal)
+// stats gives the statistics of the terms.
+func (t *terms) stats() *FailpointStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st := &FailpointStats{
+		Terms:       t.desc,
+		Evaluations: t.evals,
+		Hits:        t.counter,
+		FirstHit:    t.firstHit,
+		LastHit:     t.lastHit,
+		PerTerm:     make([]TermStats, len(t.chain)),
 	}
-
-	j := i
-	for i < len(desc) {
-		if desc[i] < '0' || desc[i] > '9' {
-			break
+	for i, term := range t.chain {
+		st.PerTerm[i] = TermStats{
+			Term:      term.desc,
+			Hits:      term.hits,
+			Remaining: term.remaining(),
+			FirstHit:  term.firstHit,
+			LastHit:   term.lastHit,
 		}
-		i++
-	}
-	if j == i {
-		return desc[:i], float64(intVal)
 	}
+	return st
+}
 
-	f := float64(0)
-	if _, err = fmt.Sscanf(desc[:i], "%f", &f); err != nil {
-		return "", nil
+// resetCounts zeroes the counters of the terms, leaving the state of the
+// modifiers as it is.
+func (t *terms) resetCounts() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.counter, t.evals = 0, 0
+	t.firstHit, t.lastHit = time.Time{}, time.Time{}
+	for _, term := range t.chain {
+		term.hits = 0
+		term.firstHit, term.lastHit = time.Time{}, time.Time{}
 	}
-	return desc[:i], f
 }
 
-// parseAct parses an action
-// <act> :: "off" | "return" | "sleep" | "panic" | "break" | "print"
-func parseAct(desc string) (string, actFunc) {
-	for k, v := range actMap {
-		if strings.HasPrefix(desc, k) {
-			return k, v
+// remaining gives the number of executions left to the N* modifiers of the
+// term, or -1 if it has none; the lock of the parent terms must be held.
+func (t *term) remaining() int {
+	left := -1
+	for _, m := range t.mods.(*modList).l {
+		if mc, ok := m.(*modCount); ok && (left < 0 || mc.c < left) {
+			left = mc.c
 		}
 	}
-	return "", nil
+	return left
 }
 
-// <val> :: <int> | <string> | <bool> | <nothing>
-func parseVal(desc string) (string, interface{}) {
-	// return => struct{}
-	if len(desc) == 0 {
-		return "", struct{}{}
-	}
-	// malformed
-	if len(desc) == 1 || desc[0] != '(' {
-		return "", nil
-	}
-	// return() => struct{}
-	if desc[1] == ')' {
-		return "()", struct{}{}
-	}
-	// return("s") => string
-	s := ""
-	n, err := fmt.Sscanf(desc[1:], "%q", &s)
-	if n == 1 && err == nil {
-		return desc[:len(s)+4], s
-	}
-	// return(1) => int
-	v := 0
-	n, err = fmt.Sscanf(desc[1:], "%d", &v)
-	if n == 1 && err == nil {
-		return desc[:len(fmt.Sprintf("%d", v))+2], v
-	}
-	// return(true) => bool
-	b := false
-	n, err = fmt.Sscanf(desc[1:], "%t", &b)
-	if n == 1 && err == nil {
-		return desc[:len(fmt.Sprintf("%t", b))+2], b
+// reseed restarts the random stream of the terms from the current seed.
+func (t *terms) reseed() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rnd = newRand(t.fpath)
+}
+
+// releasePaused wakes up the goroutines currently blocked in pause.
+// Goroutines reaching the pause afterwards block again.
+func (t *terms) releasePaused() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	close(t.release)
+	t.release = make(chan struct{})
+}
+
+func (t *terms) pausedCount() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.paused
+}
+
+func (t *terms) sleepingCount() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sleeping
+}
+
+// stop wakes up all the goroutines blocked on the terms; it is called once
+// the terms are cleared or replaced.
+func (t *terms) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	select {
+	case <-t.done:
+	default:
+		close(t.done)
 	}
-	// unknown type; malformed input?
-	return "", nil
 }
 
 type actFunc func(*term) interface{}
@@ -284,23 +384,68 @@ type actFunc func(*term) interface{}
 var actMap = map[string]actFunc{
 	"off":    actOff,
 	"return": actReturn,
+	"error":  actError,
 	"sleep":  actSleep,
+	"pause":  actPause,
 	"panic":  actPanic,
 	"break":  actBreak,
 	"print":  actPrint,
 }
 
+// blockingActs are the actions which block or don't return: their trigger
+// event is sent before they run, with their argument rather than their result.
+var blockingActs = map[string]bool{
+	"sleep": true,
+	"pause": true,
+	"panic": true,
+	"break": true,
+}
+
 func (t *term) do() interface{} { return t.act(t) }
 
 func actOff(_ *term) interface{} { return nil }
 
 func actReturn(t *term) interface{} { return t.val }
 
+// FailpointError is the error value produced by the "error" action. Callers
+// can use errors.As to tell an injected error apart from a genuine one.
+type FailpointError struct {
+	// Name is the failpoint that produced the error.
+	Name string
+	// Err is the underlying error described by the term.
+	Err error
+}
+
+func (e *FailpointError) Error() string { return e.Err.Error() }
+
+func (e *FailpointError) Unwrap() error { return e.Err }
+
+func actError(t *term) interface{} {
+	var err error
+	switch v := t.val.(type) {
+	case error:
+		err = v
+	case string:
+		err = errors.New(v)
+	case struct{}:
+		err = errors.New("failpoint error: " + t.parent.fpath)
+	default:
+		err = fmt.Errorf("%v", v)
+	}
+	return &FailpointError{Name: t.parent.fpath, Err: err}
+}
+
 func actSleep(t *term) interface{} {
 	var dur time.Duration
 	switch v := t.val.(type) {
 	case int:
 		dur = time.Duration(v) * time.Millisecond
+	case time.Duration:
+		dur = v
+	case delay:
+		t.parent.mu.Lock()
+		dur = v.sample(t.parent.rnd)
+		t.parent.mu.Unlock()
 	case string:
 		vDur, err := time.ParseDuration(v)
 		if err != nil {
@@ -312,7 +457,42 @@ func actSleep(t *term) interface{} {
 		fmt.Printf("failpoint: ignoring sleep(%v) on %s\n", v, t.parent.fpath)
 		return nil
 	}
-	time.Sleep(dur)
+	p := t.parent
+	p.mu.Lock()
+	p.sleeping++
+	p.mu.Unlock()
+
+	timer := time.NewTimer(dur)
+	defer timer.Stop()
+	// wake up early if the terms are cleared or replaced in the meantime
+	select {
+	case <-timer.C:
+	case <-p.done:
+	case <-shutdown:
+	}
+
+	p.mu.Lock()
+	p.sleeping--
+	p.mu.Unlock()
+	return nil
+}
+
+func actPause(t *term) interface{} {
+	p := t.parent
+	p.mu.Lock()
+	release := p.release
+	p.paused++
+	p.mu.Unlock()
+
+	select {
+	case <-release:
+	case <-p.done:
+	case <-shutdown:
+	}
+
+	p.mu.Lock()
+	p.paused--
+	p.mu.Unlock()
 	return nil
 }
 
@@ -15,8 +15,12 @@
 package runtime
 
 import (
+	"errors"
 	"reflect"
 	"testing"
+	"time"
+
+	fpterms "go.etcd.io/gofail/terms"
 )
 
 func TestTermsString(t *testing.T) {
@@ -46,6 +50,125 @@ func TestTermsString(t *testing.T) {
 	}
 }
 
+func TestTermsModifiers(t *testing.T) {
+	tests := []struct {
+		desc  string
+		weval []string
+	}{
+		{`after(2)return("abc")`, []string{"", "", "abc", "abc"}},
+		{`after(4)1*return("abc")`, []string{"", "", "", "", "abc", "", ""}},
+		{`every(3)return("abc")`, []string{"", "", "abc", "", "", "abc", ""}},
+		{`every(1)return("abc")`, []string{"abc", "abc"}},
+		{`every(2)2*return("abc")`, []string{"", "abc", "", "abc", "", ""}},
+		{`every(2)return("abc")->return("def")`, []string{"def", "abc", "def", "abc"}},
+		{`rate(2/h)return("abc")`, []string{"abc", "abc", "", ""}},
+		{`rate(1/1h30m)return("abc")->return("def")`, []string{"abc", "def", "def"}},
+		{`after(1)rate(1/h)return("abc")`, []string{"", "abc", ""}},
+	}
+	for _, tt := range tests {
+		ter, err := newTerms("test", tt.desc)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.desc, err)
+		}
+		if ter.String() != tt.desc {
+			t.Fatalf("got description %q, expected %q", ter.String(), tt.desc)
+		}
+		for i, w := range tt.weval {
+			v := ter.eval()
+			if v == nil && w == "" {
+				continue
+			}
+			if v != w {
+				t.Fatalf("%s: evaluation %d got %v, expected %q", tt.desc, i, v, w)
+			}
+		}
+	}
+
+	for _, desc := range []string{
+		`after(-1)return`,
+		`after(x)return`,
+		`every(0)return`,
+		`rate(0/s)return`,
+		`rate(10)return`,
+		`rate(10/parsec)return`,
+		`after(1return`,
+	} {
+		if _, err := newTerms("test", desc); err == nil {
+			t.Fatalf("%s: expected a parse error", desc)
+		}
+	}
+}
+
+func TestTermsRate(t *testing.T) {
+	ter, err := newTerms("test", `rate(2/20ms)return`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if ter.eval() != nil {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Fatalf("got %d evaluations allowed, expected 2", allowed)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if ter.eval() == nil {
+		t.Fatal("expected the evaluation to be allowed once the period is over")
+	}
+}
+
+func TestTermsTimeWindow(t *testing.T) {
+	tests := []struct {
+		desc  string
+		weval string
+	}{
+		{`for(1h)return("abc")->return("def")`, "abc"},
+		{`for(0s)return("abc")->return("def")`, "def"},
+		{`delay(1h)return("abc")->return("def")`, "def"},
+		{`delay(0)return("abc")->return("def")`, "abc"},
+		{`delay("1h")for(1h)return("abc")->return("def")`, "def"},
+		{`for(1h)delay(1h)return("abc")->return("def")`, "def"},
+	}
+	for _, tt := range tests {
+		ter, err := newTerms("test", tt.desc)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.desc, err)
+		}
+		if v := ter.eval(); v != tt.weval {
+			t.Fatalf("%s: got %v, expected %q", tt.desc, v, tt.weval)
+		}
+	}
+
+	// the window of for starts after the preceding delays
+	ter, err := newTerms("test", `delay(1s)delay(500ms)for(2s)return`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start := ter.chain[0].mods.(*modList).l[2].(*modFor).start; start != 1500*time.Millisecond {
+		t.Fatalf("got window start %v, expected %v", start, 1500*time.Millisecond)
+	}
+
+	ter, err = newTerms("test", `for(20ms)return("abc")->delay(20ms)return("def")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ter.eval(); v != "abc" {
+		t.Fatalf("got %v, expected %q", v, "abc")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if v := ter.eval(); v != "def" {
+		t.Fatalf("got %v, expected %q", v, "def")
+	}
+
+	for _, desc := range []string{`delay(-1s)return`, `for(soon)return`, `for()return`} {
+		if _, err := newTerms("test", desc); err == nil {
+			t.Fatalf("%s: expected a parse error", desc)
+		}
+	}
+}
+
 func TestTermsTypes(t *testing.T) {
 	tests := []struct {
 		desc  string
@@ -56,20 +179,204 @@ func TestTermsTypes(t *testing.T) {
 		{`return(true)`, true},
 		{`return(1)`, 1},
 		{`return()`, struct{}{}},
+		{`return("a\"b)")`, `a"b)`},
+		{`return(-1)`, -1},
+		{`return(0.5)`, 0.5},
+		{`return(-2.5e3)`, -2500.0},
+		{`return(1.5s)`, 1500 * time.Millisecond},
+		{`return(-1h30m)`, -90 * time.Minute},
+		{`return(10µs)`, 10 * time.Microsecond},
+		{`return(b"\x00ab")`, []byte("\x00ab")},
+		{`return({"a": [1, "b"], "c": null})`, map[string]interface{}{"a": []interface{}{1.0, "b"}, "c": nil}},
+		{`return([true, {"x": "y)"}])`, []interface{}{true, map[string]interface{}{"x": "y)"}}},
+		{`return(false)`, false},
 	}
 	for _, tt := range tests {
 		ter, err := newTerms("test", tt.desc)
 		if err != nil {
-			t.Fatal(err)
+			t.Fatalf("%s: %v", tt.desc, err)
 		}
 		v := ter.eval()
 		if v == nil && tt.weval == nil {
 			continue
 		}
 		if !reflect.DeepEqual(v, tt.weval) {
-			t.Fatalf("got %v, expected %v", v, tt.weval)
+			t.Fatalf("%s: got %#v, expected %#v", tt.desc, v, tt.weval)
+		}
+	}
+
+	for _, desc := range []string{
+		`return(1.5x)`,
+		`return(Inf)`,
+		`return(0x10)`,
+		`return(--1)`,
+		`return({"a": 1)`,
+		`return("abc)`,
+		`return(truth)`,
+	} {
+		_, err := newTerms("test", desc)
+		var pe *ParseError
+		if !errors.As(err, &pe) || !errors.Is(err, ErrBadParse) {
+			t.Fatalf("%s: expected a parse error, got %v", desc, err)
+		}
+	}
+}
+
+func TestTermsActions(t *testing.T) {
+	for _, a := range fpterms.Actions {
+		if _, ok := actMap[a.Name]; !ok {
+			t.Fatalf("action %q of the terms grammar is not implemented", a.Name)
 		}
 	}
+	if len(actMap) != len(fpterms.Actions) {
+		t.Fatalf("got %d actions, the terms grammar has %d", len(actMap), len(fpterms.Actions))
+	}
+}
+
+func TestTermsError(t *testing.T) {
+	tests := []struct {
+		desc string
+		wmsg string
+	}{
+		{`error("disk full")`, "disk full"},
+		{`error`, "failpoint error: test"},
+		{`error()`, "failpoint error: test"},
+		{`error(1)`, "1"},
+	}
+	for _, tt := range tests {
+		ter, err := newTerms("test", tt.desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, ok := ter.eval().(error)
+		if !ok {
+			t.Fatalf("%s: expected an error value", tt.desc)
+		}
+		if v.Error() != tt.wmsg {
+			t.Fatalf("%s: got %q, expected %q", tt.desc, v.Error(), tt.wmsg)
+		}
+		var fpErr *FailpointError
+		if !errors.As(v, &fpErr) || fpErr.Name != "test" {
+			t.Fatalf("%s: expected a *FailpointError from failpoint %q, got %#v", tt.desc, "test", v)
+		}
+	}
+}
+
+func TestTermsRegisteredValue(t *testing.T) {
+	errSentinel := errors.New("proposal dropped")
+	type point struct{ x, y int }
+	RegisterValue("ErrSentinel", errSentinel)
+	RegisterValue("pkg.Point", point{1, 2})
+
+	ter, err := newTerms("test", `return(@ErrSentinel)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ter.eval(); v != errSentinel {
+		t.Fatalf("got %v, expected the registered error", v)
+	}
+
+	ter, err = newTerms("test", `error(@ErrSentinel)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ter.eval().(error); !errors.Is(v, errSentinel) {
+		t.Fatalf("got %v, expected an error matching the registered error", v)
+	}
+
+	ter, err = newTerms("test", `1*return(@pkg.Point)->return("abc")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := ter.eval(); !reflect.DeepEqual(v, point{1, 2}) {
+		t.Fatalf("got %v, expected %v", v, point{1, 2})
+	}
+
+	if _, err = newTerms("test", `return(@ErrUnknown)`); !errors.Is(err, ErrNoValue) {
+		t.Fatalf("got %v, expected %v", err, ErrNoValue)
+	}
+}
+
+func TestTermsDelay(t *testing.T) {
+	tests := []struct {
+		desc   string
+		wdelay delay
+	}{
+		{`sleep("10ms".."200ms")`, delayRange{10 * time.Millisecond, 200 * time.Millisecond}},
+		{`sleep(10..200)`, delayRange{10 * time.Millisecond, 200 * time.Millisecond}},
+		{`sleep(1.5s..2s)`, delayRange{1500 * time.Millisecond, 2 * time.Second}},
+		{`sleep("1s".."1s")`, delayRange{time.Second, time.Second}},
+		{`sleep(exp("50ms"))`, delayExp{50 * time.Millisecond}},
+		{`sleep(normal("100ms","20ms"))`, delayNormal{100 * time.Millisecond, 20 * time.Millisecond}},
+		{`sleep(normal("100ms", "20ms"))`, delayNormal{100 * time.Millisecond, 20 * time.Millisecond}},
+		{`1*sleep(exp(5))->sleep(1)`, delayExp{5 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		ter, err := newTerms("test", tt.desc)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.desc, err)
+		}
+		if !reflect.DeepEqual(ter.chain[0].val, tt.wdelay) {
+			t.Fatalf("%s: got %#v, expected %#v", tt.desc, ter.chain[0].val, tt.wdelay)
+		}
+	}
+
+	for _, desc := range []string{
+		`sleep("200ms".."10ms")`,
+		`sleep("10ms"..)`,
+		`sleep(exp("50ms")`,
+		`sleep(normal("100ms"))`,
+		`sleep(normal("100ms","-20ms"))`,
+		`return("10ms".."200ms")`,
+		`return(exp("50ms"))`,
+	} {
+		if _, err := newTerms("test", desc); err == nil {
+			t.Fatalf("%s: expected a parse error", desc)
+		}
+	}
+
+	rnd := newRand("test")
+	r := delayRange{10 * time.Millisecond, 20 * time.Millisecond}
+	for i := 0; i < 100; i++ {
+		if d := r.sample(rnd); d < r.min || d > r.max {
+			t.Fatalf("sample %v out of range [%v, %v]", d, r.min, r.max)
+		}
+	}
+	n := delayNormal{time.Millisecond, time.Second}
+	for i := 0; i < 100; i++ {
+		if d := n.sample(rnd); d < 0 {
+			t.Fatalf("negative sample %v", d)
+		}
+	}
+}
+
+func TestTermsSeed(t *testing.T) {
+	defer SetSeed(Seed())
+	SetSeed(42)
+
+	evalN := func(name string) []interface{} {
+		ter, err := newTerms(name, `50.0%return(1)`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var vals []interface{}
+		for i := 0; i < 64; i++ {
+			vals = append(vals, ter.eval())
+		}
+		return vals
+	}
+
+	first := evalN("test")
+	if !reflect.DeepEqual(first, evalN("test")) {
+		t.Fatal("expected the same decisions for the same seed and failpoint name")
+	}
+	if reflect.DeepEqual(first, evalN("other")) {
+		t.Fatal("expected different decisions for a different failpoint name")
+	}
+	SetSeed(43)
+	if reflect.DeepEqual(first, evalN("test")) {
+		t.Fatal("expected different decisions for a different seed")
+	}
 }
 
 func TestTermsCounter(t *testing.T) {
@@ -0,0 +1,59 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtime
+
+import (
+	"fmt"
+	"sync"
+
+	fpterms "go.etcd.io/gofail/terms"
+)
+
+var (
+	ErrNoValue = fmt.Errorf("failpoint: value is not registered")
+
+	values = make(map[string]interface{})
+	// valuesMu protects the values map
+	valuesMu sync.RWMutex
+)
+
+// RegisterValue makes v available to failpoint terms under the given name,
+// so that a term such as `return(@name)` or `error(@name)` yields exactly v.
+// This allows failpoints to return sentinel errors that can be matched with
+// errors.Is, or any other value that can't be written as a literal.
+//
+// Registering a name again replaces the previous value; terms that were
+// enabled before keep the value they resolved at the time.
+func RegisterValue(name string, v interface{}) {
+	if !fpterms.IsValueName(name) {
+		panic(fmt.Sprintf("failpoint value name %q is invalid.", name))
+	}
+	if v == nil {
+		panic(fmt.Sprintf("failpoint value %s must not be nil.", name))
+	}
+	valuesMu.Lock()
+	defer valuesMu.Unlock()
+	values[name] = v
+}
+
+func lookupValue(name string) (interface{}, error) {
+	valuesMu.RLock()
+	defer valuesMu.RUnlock()
+	v, ok := values[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: @%s", ErrNoValue, name)
+	}
+	return v, nil
+}
@@ -0,0 +1,181 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package terms parses, validates and formats failpoint terms such as
+// `1*return("abc")->sleep(10)`. Unlike the gofail runtime, importing it has no
+// side effects, so tools can check terms before sending them to a process.
+package terms
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Terms is a chain of terms separated by "->". A failpoint executes the first
+// term whose modifiers allow it.
+type Terms struct {
+	Chain []*Term
+}
+
+// Term is a single executable unit of the terms chain.
+type Term struct {
+	// Mods are the modifiers of the term, in the order they were written.
+	Mods []Mod
+	// Action is the name of the action, one of Actions.
+	Action string
+	// Value is the argument of the action: struct{}{} when there is none, a
+	// string, []byte, int, float64, time.Duration, bool, a JSON value, a Ref
+	// to a registered value, or a Range, Exp or Normal delay for sleep.
+	Value interface{}
+}
+
+// Mod is a term modifier deciding whether the term is executed.
+type Mod interface {
+	fmt.Stringer
+	mod()
+}
+
+// Prob allows the term with the given probability, written as "P%".
+type Prob struct{ Percent float64 }
+
+// Count allows the term the first N times, written as "N*".
+type Count struct{ N int }
+
+// After skips the first N evaluations, written as "after(N)".
+type After struct{ N int }
+
+// Every allows every N-th evaluation, written as "every(N)".
+type Every struct{ N int }
+
+// Rate allows at most Limit evaluations per period, written as "rate(N/unit)".
+type Rate struct {
+	Limit int
+	Per   time.Duration
+}
+
+// Delay arms the term once D has elapsed since it was enabled, written as
+// "delay(D)".
+type Delay struct{ D time.Duration }
+
+// For keeps the term active for D once the delays preceding it have elapsed,
+// written as "for(D)".
+type For struct{ D time.Duration }
+
+func (Prob) mod()  {}
+func (Count) mod() {}
+func (After) mod() {}
+func (Every) mod() {}
+func (Rate) mod()  {}
+func (Delay) mod() {}
+func (For) mod()   {}
+
+func (m Prob) String() string  { return strconv.FormatFloat(m.Percent, 'f', -1, 64) + "%" }
+func (m Count) String() string { return strconv.Itoa(m.N) + "*" }
+func (m After) String() string { return "after(" + strconv.Itoa(m.N) + ")" }
+func (m Every) String() string { return "every(" + strconv.Itoa(m.N) + ")" }
+func (m Delay) String() string { return "delay(" + m.D.String() + ")" }
+func (m For) String() string   { return "for(" + m.D.String() + ")" }
+
+func (m Rate) String() string {
+	per := m.Per.String()
+	switch m.Per {
+	case time.Second:
+		per = "s"
+	case time.Minute:
+		per = "m"
+	case time.Hour:
+		per = "h"
+	}
+	return "rate(" + strconv.Itoa(m.Limit) + "/" + per + ")"
+}
+
+// Ref refers to a value registered with the gofail runtime, written as
+// "@name". It is resolved when the terms are enabled.
+type Ref string
+
+// Range is a sleep duration picked uniformly in [Min, Max], written as
+// "min..max".
+type Range struct{ Min, Max time.Duration }
+
+// Exp is an exponentially distributed sleep duration, written as
+// "exp(mean)".
+type Exp struct{ Mean time.Duration }
+
+// Normal is a normally distributed sleep duration, written as
+// "normal(mean,stddev)".
+type Normal struct{ Mean, Stddev time.Duration }
+
+// String formats the terms in their canonical form, which parses back to the
+// same terms.
+func (t *Terms) String() string {
+	s := make([]string, len(t.Chain))
+	for i, term := range t.Chain {
+		s[i] = term.String()
+	}
+	return strings.Join(s, "->")
+}
+
+func (t *Term) String() string {
+	var b strings.Builder
+	for _, m := range t.Mods {
+		b.WriteString(m.String())
+	}
+	b.WriteString(t.Action)
+	if _, ok := t.Value.(struct{}); !ok && t.Value != nil {
+		b.WriteString("(" + formatValue(t.Value) + ")")
+	}
+	return b.String()
+}
+
+// Format formats the terms in their canonical form.
+func Format(t *Terms) string { return t.String() }
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []byte:
+		return "b" + strconv.Quote(string(v))
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		// keep it a float when parsed back
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s
+	case time.Duration:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case Ref:
+		return "@" + string(v)
+	case Range:
+		return v.Min.String() + ".." + v.Max.String()
+	case Exp:
+		return "exp(" + v.Mean.String() + ")"
+	case Normal:
+		return "normal(" + v.Mean.String() + "," + v.Stddev.String() + ")"
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(b)
+	}
+}
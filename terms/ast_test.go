@@ -0,0 +1,63 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terms
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	got, err := Parse(`50%after(2)1*return("abc")->delay("1s")for(2s)sleep(10ms..20ms)->rate(3/s)error(@ErrX)->off`)
+	require.NoError(t, err)
+	want := &Terms{Chain: []*Term{
+		{Mods: []Mod{Prob{50}, After{2}, Count{1}}, Action: "return", Value: "abc"},
+		{Mods: []Mod{Delay{time.Second}, For{2 * time.Second}}, Action: "sleep", Value: Range{10 * time.Millisecond, 20 * time.Millisecond}},
+		{Mods: []Mod{Rate{3, time.Second}}, Action: "error", Value: Ref("ErrX")},
+		{Action: "off", Value: struct{}{}},
+	}}
+	assert.Equal(t, want, got)
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		desc string
+		want string
+	}{
+		{`off`, `off`},
+		{`return()`, `return`},
+		{`1*return("abc")->print`, `1*return("abc")->print`},
+		{`12.5%every(3)return(-1)`, `12.5%every(3)return(-1)`},
+		{`after(4)rate(10/100ms)return(1.0)`, `after(4)rate(10/100ms)return(1.0)`},
+		{`delay(5000)for("1m")return(1h30m)`, `delay(5s)for(1m0s)return(1h30m0s)`},
+		{`return(b"\x00")`, `return(b"\x00")`},
+		{`return({"a": [1, true]})`, `return({"a":[1,true]})`},
+		{`error(@ErrX)`, `error(@ErrX)`},
+		{`sleep(exp("10ms"))->sleep(normal(10ms, 2ms))->sleep(1..2)`, `sleep(exp(10ms))->sleep(normal(10ms,2ms))->sleep(1ms..2ms)`},
+	}
+	for _, tt := range tests {
+		parsed, err := Parse(tt.desc)
+		require.NoError(t, err, tt.desc)
+		assert.Equal(t, tt.want, Format(parsed), tt.desc)
+
+		// the canonical form parses back to the same terms
+		reparsed, err := Parse(tt.want)
+		require.NoError(t, err, tt.want)
+		assert.Equal(t, parsed, reparsed, tt.want)
+	}
+}
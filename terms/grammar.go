@@ -0,0 +1,91 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terms
+
+import "sort"
+
+// Grammar is the EBNF of a terms string.
+const Grammar = `Terms     = Term { "->" Term } .
+Term      = { Mode } Action [ Value ] .
+Mode      = ( float | int ) "%" | int "*" | "after(" int ")" | "every(" int ")"
+          | "rate(" int "/" unit ")" | "delay(" Duration ")" | "for(" Duration ")" .
+Action    = "off" | "return" | "error" | "sleep" | "pause" | "panic" | "break" | "print" .
+Value     = "(" [ Literal | Delay ] ")" .
+Literal   = string | "b" string | int | float | duration | "true" | "false"
+          | json_object | json_array | "@" name .
+Delay     = Duration ".." Duration | "exp(" Duration ")" | "normal(" Duration "," Duration ")" .
+Duration  = string | duration | int .`
+
+// Spec describes an element of the grammar.
+type Spec struct {
+	// Name is the keyword of the element.
+	Name string
+	// Syntax shows how the element is written.
+	Syntax string
+	// Doc is a one line description of the element.
+	Doc string
+}
+
+// Actions lists the actions a term may execute.
+var Actions = []Spec{
+	{"off", "off", "does nothing"},
+	{"return", "return(v)", "makes the failpoint return v"},
+	{"error", `error("msg")`, "makes the failpoint return an error"},
+	{"sleep", "sleep(d)", "sleeps for the duration d, in milliseconds if it is an int"},
+	{"pause", "pause", "blocks until the failpoint is released or disabled"},
+	{"panic", "panic", "panics"},
+	{"break", "break", "attaches gdb to the process"},
+	{"print", "print", "prints the failpoint path"},
+}
+
+// Modifiers lists the modifiers deciding whether a term is executed.
+var Modifiers = []Spec{
+	{"%", "P%", "executes the term with probability P percent"},
+	{"*", "N*", "executes the term the first N times"},
+	{"after", "after(N)", "skips the first N evaluations"},
+	{"every", "every(N)", "executes the term every N-th evaluation"},
+	{"rate", "rate(N/unit)", "executes the term at most N times per unit of time"},
+	{"delay", "delay(d)", "arms the term once d has elapsed since it was enabled"},
+	{"for", "for(d)", "keeps the term active for d after the delays preceding it"},
+}
+
+func isAction(name string) bool {
+	for _, a := range Actions {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func actionNames() []string {
+	names := make([]string, len(Actions))
+	for i, a := range Actions {
+		names[i] = a.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// namedMods returns the modifiers written as a function call.
+func namedMods() []string {
+	var names []string
+	for _, m := range Modifiers {
+		if m.Syntax != m.Name && m.Syntax[len(m.Name)] == '(' {
+			names = append(names, m.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,627 @@
+// Copyright 2016 CoreOS, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terms
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrBadParse = fmt.Errorf("failpoint: could not parse terms")
+
+// ParseError describes why a failpoint terms string could not be parsed.
+// It matches ErrBadParse with errors.Is.
+type ParseError struct {
+	// Input is the string being parsed.
+	Input string
+	// Offset is the byte offset in Input at which parsing failed.
+	Offset int
+	// Token is the offending token at Offset; it is empty at the end of Input.
+	Token string
+	// Expected describes what was expected at Offset.
+	Expected string
+}
+
+func (e *ParseError) Error() string {
+	found := "end of input"
+	if len(e.Token) > 0 {
+		found = strconv.Quote(e.Token)
+	}
+	return fmt.Sprintf("failpoint: could not parse %q at offset %d: expected %s, found %s",
+		e.Input, e.Offset, e.Expected, found)
+}
+
+func (e *ParseError) Is(target error) bool { return target == ErrBadParse }
+
+// parser keeps track of the position in a terms string, so that errors can
+// point at the offending token.
+type parser struct {
+	in  string
+	pos int
+}
+
+func (p *parser) rest() string { return p.in[p.pos:] }
+
+func (p *parser) eof() bool { return p.pos == len(p.in) }
+
+// accept consumes s if the input continues with it.
+func (p *parser) accept(s string) bool {
+	if !strings.HasPrefix(p.rest(), s) {
+		return false
+	}
+	p.pos += len(s)
+	return true
+}
+
+func (p *parser) expect(s string) error {
+	if !p.accept(s) {
+		return p.errorf("%s", strconv.Quote(s))
+	}
+	return nil
+}
+
+func (p *parser) errorf(expected string, args ...interface{}) error {
+	return p.errorAt(p.pos, expected, args...)
+}
+
+func (p *parser) errorAt(offset int, expected string, args ...interface{}) error {
+	return &ParseError{
+		Input:    p.in,
+		Offset:   offset,
+		Token:    tokenAt(p.in, offset),
+		Expected: fmt.Sprintf(expected, args...),
+	}
+}
+
+// tokenAt returns the token starting at the given offset: a word, a quoted
+// string, or a single character.
+func tokenAt(s string, offset int) string {
+	s = s[offset:]
+	if len(s) == 0 {
+		return ""
+	}
+	if s[0] == '"' {
+		if q, err := strconv.QuotedPrefix(s); err == nil {
+			return q
+		}
+		return s
+	}
+	i := 0
+	for i < len(s) && isValueNameChar(s[i]) {
+		i++
+	}
+	if i == 0 {
+		return s[:1]
+	}
+	return s[:i]
+}
+
+// Parse parses a terms string such as `1*return("abc")->sleep(10)`. It returns
+// a *ParseError if the string is not valid.
+// <terms> :: <term> ( "->" <term> )*
+func Parse(desc string) (*Terms, error) {
+	p := &parser{in: desc}
+	t := &Terms{}
+	for {
+		term, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		t.Chain = append(t.Chain, term)
+		if p.eof() {
+			return t, nil
+		}
+		if !p.accept("->") {
+			return nil, p.errorf(`"->" or end of terms`)
+		}
+	}
+}
+
+// Validate reports whether desc is a valid terms string, returning the same
+// *ParseError as Parse if it is not.
+func Validate(desc string) error {
+	_, err := Parse(desc)
+	return err
+}
+
+// FailpointTerms is an entry of a list of failpoints, giving the terms of a
+// failpoint, or of the failpoints matching a pattern.
+type FailpointTerms struct {
+	Name  string
+	Terms string
+}
+
+// ParseFailpoints parses a list of failpoints with their terms in the format
+// of GOFAIL_FAILPOINTS, <name>=<terms>[;<name>=<terms>]*, and returns the terms
+// by failpoint name. The offset of a *ParseError is relative to the whole list.
+// When several entries are invalid, the error joins a *ParseError for each.
+func ParseFailpoints(fps string) (map[string]string, error) {
+	list, err := ParseFailpointList(fps)
+	if err != nil {
+		return nil, err
+	}
+	fpMap := make(map[string]string, len(list))
+	for _, fp := range list {
+		fpMap[fp.Name] = fp.Terms
+	}
+	return fpMap, nil
+}
+
+// ParseFailpointList is like ParseFailpoints, but returns the entries in the
+// order of the list, which matters when patterns overlap.
+func ParseFailpointList(fps string) ([]FailpointTerms, error) {
+	var list []FailpointTerms
+	p := &parser{in: fps}
+
+	var errs []error
+	offset := 0
+	for _, fp := range strings.Split(fps, ";") {
+		start := offset
+		offset += len(fp) + len(";")
+		if len(fp) == 0 {
+			continue
+		}
+		name, terms, ok := strings.Cut(fp, "=")
+		if !ok {
+			errs = append(errs, p.errorAt(start+len(fp), `"=" followed by terms`))
+			continue
+		}
+		if len(name) == 0 {
+			errs = append(errs, p.errorAt(start, "a failpoint name"))
+			continue
+		}
+		if err := Validate(terms); err != nil {
+			// point at the offending token in the whole string
+			pe := err.(*ParseError)
+			errs = append(errs, p.errorAt(start+len(name)+len("=")+pe.Offset, "%s", pe.Expected))
+			continue
+		}
+		list = append(list, FailpointTerms{Name: name, Terms: terms})
+	}
+	switch len(errs) {
+	case 0:
+		return list, nil
+	case 1:
+		return nil, errs[0]
+	default:
+		return nil, errors.Join(errs...)
+	}
+}
+
+// <term> :: <mod>* <act> [ "(" <val> ")" ]
+func (p *parser) parseTerm() (*Term, error) {
+	mods, err := p.parseMods()
+	if err != nil {
+		return nil, err
+	}
+	act, err := p.parseAct()
+	if err != nil {
+		return nil, err
+	}
+	valPos := p.pos
+	val, err := p.parseVal()
+	if err != nil {
+		return nil, err
+	}
+	if isDelay(val) && act != "sleep" {
+		return nil, p.errorAt(valPos+1, "a value, as delays are only accepted by sleep")
+	}
+	return &Term{Mods: mods, Action: act, Value: val}, nil
+}
+
+// <mod> :: <float> "%" | <int> "%" | <int> "*" | <named-mod>
+func (p *parser) parseMods() ([]Mod, error) {
+	var mods []Mod
+	for {
+		if m, err := p.parseNamedMod(); err != nil {
+			return nil, err
+		} else if m != nil {
+			mods = append(mods, m)
+			continue
+		}
+
+		start := p.pos
+		n := len(p.rest()) - len(strings.TrimLeft(p.rest(), "0123456789."))
+		if n == 0 {
+			break
+		}
+		s := p.in[p.pos : p.pos+n]
+		p.pos += n
+		switch {
+		case p.accept("%"):
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil || !isDecimal(s) {
+				return nil, p.errorAt(start, "a percentage")
+			}
+			mods = append(mods, Prob{v})
+		case p.accept("*"):
+			v, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, p.errorAt(start, "a count")
+			}
+			mods = append(mods, Count{v})
+		default:
+			return nil, p.errorf(`"%%" or "*"`)
+		}
+	}
+	return mods, nil
+}
+
+// parseNamedMod parses a modifier written as a function call; it returns a
+// nil mod if the input doesn't continue with one.
+// <named-mod> :: "after(" <int> ")" | "every(" <int> ")" | "rate(" <int> "/" <unit> ")" | "delay(" <dur> ")" | "for(" <dur> ")"
+func (p *parser) parseNamedMod() (Mod, error) {
+	var name string
+	for _, n := range namedMods() {
+		if strings.HasPrefix(p.rest(), n+"(") {
+			name = n
+			break
+		}
+	}
+	if len(name) == 0 {
+		return nil, nil
+	}
+	p.pos += len(name) + 1
+
+	var m Mod
+	argPos := p.pos
+	switch name {
+	case "after", "every":
+		n := scanNumber(p.rest())
+		v, err := strconv.Atoi(p.rest()[:n])
+		if err != nil || v < 0 {
+			return nil, p.errorf("a non-negative int")
+		}
+		if name == "every" && v < 1 {
+			return nil, p.errorf("a positive int")
+		}
+		p.pos += n
+		if name == "after" {
+			m = After{v}
+		} else {
+			m = Every{v}
+		}
+	case "rate":
+		n := scanNumber(p.rest())
+		limit, err := strconv.Atoi(p.rest()[:n])
+		if err != nil || limit < 1 {
+			return nil, p.errorf("a positive int")
+		}
+		p.pos += n
+		if err := p.expect("/"); err != nil {
+			return nil, err
+		}
+		unitPos := p.pos
+		i := strings.IndexByte(p.rest(), ')')
+		if i < 0 {
+			i = len(p.rest())
+		}
+		// "s" means "1s", but "100ms" is accepted as well
+		unit := p.rest()[:i]
+		if len(unit) > 0 && (unit[0] < '0' || unit[0] > '9') {
+			unit = "1" + unit
+		}
+		per, err := time.ParseDuration(unit)
+		if err != nil || per <= 0 {
+			return nil, p.errorAt(unitPos, "a unit of time such as s or 100ms")
+		}
+		p.pos += i
+		m = Rate{Limit: limit, Per: per}
+	default:
+		d, err := p.parseDuration()
+		if err != nil {
+			return nil, err
+		}
+		if d < 0 {
+			return nil, p.errorAt(argPos, "a positive duration")
+		}
+		if name == "delay" {
+			m = Delay{d}
+		} else {
+			m = For{d}
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parseAct parses an action
+// <act> :: "off" | "return" | "error" | "sleep" | "pause" | "panic" | "break" | "print"
+func (p *parser) parseAct() (string, error) {
+	i := 0
+	for i < len(p.rest()) && p.rest()[i] >= 'a' && p.rest()[i] <= 'z' {
+		i++
+	}
+	name := p.rest()[:i]
+	if !isAction(name) {
+		return "", p.errorf("an action (%s)", strings.Join(actionNames(), ", "))
+	}
+	p.pos += i
+	return name, nil
+}
+
+// <val> :: "(" [ <literal> | <delay> ] ")" | <nothing>
+func (p *parser) parseVal() (interface{}, error) {
+	// return => struct{}
+	if !p.accept("(") {
+		return struct{}{}, nil
+	}
+	// return() => struct{}
+	if p.accept(")") {
+		return struct{}{}, nil
+	}
+	// sleep("10ms".."20ms"), sleep(exp("10ms")), sleep(normal("10ms","2ms")) => delay
+	var v interface{}
+	d, err := p.parseDelay()
+	if err != nil {
+		return nil, err
+	}
+	if d != nil {
+		v = d
+	} else if v, err = p.parseLiteral(); err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// parseLiteral parses a value. Each kind of literal yields a single Go type:
+//
+//	"s", `s`   => string
+//	b"s", b`s` => []byte
+//	1, -1      => int
+//	0.5, 1e3   => float64
+//	1.5s, -1m  => time.Duration
+//	true       => bool
+//	{...}      => map[string]interface{}, decoded as JSON
+//	[...]      => []interface{}, decoded as JSON
+//	@name      => Ref, the value registered with the gofail runtime
+//
+// <literal> :: <string> | <bytes> | <int> | <float> | <duration> | <bool> | <json> | "@" <name>
+func (p *parser) parseLiteral() (interface{}, error) {
+	rest := p.rest()
+	switch {
+	case p.accept("@"):
+		i := 0
+		for i < len(p.rest()) && isValueNameChar(p.rest()[i]) {
+			i++
+		}
+		if i == 0 {
+			return nil, p.errorf("a value name")
+		}
+		name := p.rest()[:i]
+		p.pos += i
+		return Ref(name), nil
+	case isQuote(rest):
+		return p.parseString()
+	case p.accept("b"):
+		if !isQuote(p.rest()) {
+			return nil, p.errorf("a quoted string")
+		}
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return []byte(s), nil
+	case strings.HasPrefix(rest, "{"), strings.HasPrefix(rest, "["):
+		dec := json.NewDecoder(strings.NewReader(rest))
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return nil, p.errorf("a JSON value (%v)", err)
+		}
+		p.pos += int(dec.InputOffset())
+		return v, nil
+	case p.accept("true"):
+		return true, nil
+	case p.accept("false"):
+		return false, nil
+	}
+
+	n := scanNumber(rest)
+	if n == 0 {
+		return nil, p.errorf("a value")
+	}
+	s := rest[:n]
+	if v, err := strconv.Atoi(s); err == nil {
+		p.pos += n
+		return v, nil
+	}
+	if v, err := time.ParseDuration(s); err == nil {
+		p.pos += n
+		return v, nil
+	}
+	// only accept plain decimal floats, not "Inf", "NaN" or hex floats
+	if isDecimal(s) {
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			p.pos += n
+			return v, nil
+		}
+	}
+	return nil, p.errorf("a number or a duration")
+}
+
+// isQuote reports whether s starts with a Go string literal, either double
+// quoted or back-quoted.
+func isQuote(s string) bool {
+	return strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "`")
+}
+
+func (p *parser) parseString() (string, error) {
+	q, err := strconv.QuotedPrefix(p.rest())
+	if err != nil {
+		return "", p.errorf("a valid quoted string")
+	}
+	s, err := strconv.Unquote(q)
+	if err != nil {
+		return "", p.errorf("a valid quoted string")
+	}
+	p.pos += len(q)
+	return s, nil
+}
+
+// parseDelay parses a randomized duration, a Range, Exp or Normal; it returns
+// nil if the input doesn't continue with one.
+// <delay> :: <dur> ".." <dur> | "exp(" <dur> ")" | "normal(" <dur> "," <dur> ")"
+func (p *parser) parseDelay() (interface{}, error) {
+	switch {
+	case p.accept("exp("):
+		argPos := p.pos
+		mean, err := p.parseDuration()
+		if err != nil {
+			return nil, err
+		}
+		if mean < 0 {
+			return nil, p.errorAt(argPos, "a positive duration")
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return Exp{mean}, nil
+	case p.accept("normal("):
+		mean, err := p.parseDuration()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		for strings.HasPrefix(p.rest(), " ") {
+			p.pos++
+		}
+		argPos := p.pos
+		stddev, err := p.parseDuration()
+		if err != nil {
+			return nil, err
+		}
+		if stddev < 0 {
+			return nil, p.errorAt(argPos, "a positive duration")
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return Normal{mean, stddev}, nil
+	}
+
+	// only a range if a duration is followed by ".."
+	start := p.pos
+	lo, err := p.parseDuration()
+	if err != nil || !p.accept("..") {
+		p.pos = start
+		return nil, nil
+	}
+	if lo < 0 {
+		return nil, p.errorAt(start, "a positive duration")
+	}
+	hiPos := p.pos
+	hi, err := p.parseDuration()
+	if err != nil {
+		return nil, err
+	}
+	if hi < lo {
+		return nil, p.errorAt(hiPos, "a duration no less than %v", lo)
+	}
+	return Range{lo, hi}, nil
+}
+
+// parseDuration parses a duration such as "10ms" or 10ms, or an int giving
+// the duration in milliseconds.
+// <dur> :: <string> | <duration> | <int>
+func (p *parser) parseDuration() (time.Duration, error) {
+	start := p.pos
+	s := p.rest()
+	if isQuote(s) {
+		var err error
+		if s, err = p.parseString(); err != nil {
+			return 0, err
+		}
+	} else {
+		n := scanNumber(s)
+		s = s[:n]
+		p.pos += n
+	}
+	if ms, err := strconv.Atoi(s); err == nil {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, p.errorAt(start, "a duration")
+	}
+	return d, nil
+}
+
+// scanNumber returns the length of the number at the start of desc,
+// including the optional sign and unit suffixes such as in "-1.5e3" or
+// "1h30m". A ".." following the number is not part of it.
+func scanNumber(desc string) int {
+	i := 0
+	if i < len(desc) && (desc[i] == '-' || desc[i] == '+') {
+		i++
+	}
+	if i == len(desc) || desc[i] < '0' || desc[i] > '9' {
+		return 0
+	}
+	for i < len(desc) {
+		c := desc[i]
+		switch {
+		case c >= '0' && c <= '9', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c == '.' && !strings.HasPrefix(desc[i:], ".."):
+		case (c == '-' || c == '+') && (desc[i-1] == 'e' || desc[i-1] == 'E'):
+		case strings.HasPrefix(desc[i:], "µ"):
+			i += len("µ") - 1
+		default:
+			return i
+		}
+		i++
+	}
+	return i
+}
+
+// isDecimal reports whether s only has the characters of a decimal float.
+func isDecimal(s string) bool { return strings.Trim(s, "0123456789.eE+-") == "" }
+
+func isDelay(v interface{}) bool {
+	switch v.(type) {
+	case Range, Exp, Normal:
+		return true
+	}
+	return false
+}
+
+// IsValueName reports whether name may refer to a registered value, i.e.
+// whether it is made of letters, digits, '_' and '.' only.
+func IsValueName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isValueNameChar(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isValueNameChar(c byte) bool {
+	return c == '_' || c == '.' ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
@@ -0,0 +1,117 @@
+// Copyright 2026 The etcd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terms
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		desc      string
+		wOffset   int
+		wToken    string
+		wExpected string
+	}{
+		{``, 0, "", "an action (break, error, off, panic, pause, print, return, sleep)"},
+		{`retrun`, 0, "retrun", "an action (break, error, off, panic, pause, print, return, sleep)"},
+		{`return("abc")x`, 13, "x", `"->" or end of terms`},
+		{`return("abc")->`, 15, "", "an action (break, error, off, panic, pause, print, return, sleep)"},
+		{`return("abc")->->off`, 15, "-", "an action (break, error, off, panic, pause, print, return, sleep)"},
+		{`10%`, 3, "", "an action (break, error, off, panic, pause, print, return, sleep)"},
+		{`10return`, 2, "return", `"%" or "*"`},
+		{`1.5*return`, 0, "1.5", "a count"},
+		{`return("abc"`, 12, "", `")"`},
+		{`return(abc)`, 7, "abc", "a value"},
+		{`return(1.5x)`, 7, "1.5x", "a number or a duration"},
+		{`return(@)`, 8, ")", "a value name"},
+		{`after(x)return`, 6, "x", "a non-negative int"},
+		{`every(0)return`, 6, "0", "a positive int"},
+		{`rate(1/parsec)return`, 7, "parsec", "a unit of time such as s or 100ms"},
+		{`for(-1s)return`, 4, "-", "a positive duration"},
+		{`sleep("20ms".."10ms")`, 14, `"10ms"`, "a duration no less than 20ms"},
+		{`return(exp(10))`, 7, "exp", "a value, as delays are only accepted by sleep"},
+	}
+	for _, tt := range tests {
+		err := Validate(tt.desc)
+		require.Errorf(t, err, "%s: expected a parse error", tt.desc)
+		assert.ErrorIs(t, err, ErrBadParse)
+
+		var pe *ParseError
+		require.True(t, errors.As(err, &pe), "%s: expected a *ParseError, got %v", tt.desc, err)
+		assert.Equal(t, tt.desc, pe.Input)
+		assert.Equal(t, tt.wOffset, pe.Offset, tt.desc)
+		assert.Equal(t, tt.wToken, pe.Token, tt.desc)
+		assert.Equal(t, tt.wExpected, pe.Expected, tt.desc)
+	}
+}
+
+func TestParseFailpointsError(t *testing.T) {
+	fps := `failpoint1=print;failpoint2=return("a")x;failpoint3`
+	_, err := ParseFailpoints(fps)
+
+	var pe *ParseError
+	require.True(t, errors.As(err, &pe), "expected a *ParseError, got %v", err)
+	assert.Equal(t, fps, pe.Input)
+	assert.Equal(t, 39, pe.Offset)
+	assert.Equal(t, "x", pe.Token)
+	assert.Equal(t, `"->" or end of terms`, pe.Expected)
+	assert.Equal(t, `failpoint: could not parse "failpoint1=print;failpoint2=return(\"a\")x;failpoint3" at offset 39: expected "->" or end of terms, found "x"`+"\n"+
+		`failpoint: could not parse "failpoint1=print;failpoint2=return(\"a\")x;failpoint3" at offset 51: expected "=" followed by terms, found end of input`, err.Error())
+
+	_, err = ParseFailpoints("failpoint1=print;failpoint2")
+	require.True(t, errors.As(err, &pe), "expected a *ParseError, got %v", err)
+	assert.Equal(t, 27, pe.Offset)
+	assert.Equal(t, `"=" followed by terms`, pe.Expected)
+
+	// every invalid entry is reported
+	_, err = ParseFailpoints("failpoint1=retrun;failpoint2=print;failpoint3;=off")
+	assert.Equal(t, strings.Join([]string{
+		`failpoint: could not parse "failpoint1=retrun;failpoint2=print;failpoint3;=off" at offset 11: expected an action (break, error, off, panic, pause, print, return, sleep), found "retrun"`,
+		`failpoint: could not parse "failpoint1=retrun;failpoint2=print;failpoint3;=off" at offset 45: expected "=" followed by terms, found ";"`,
+		`failpoint: could not parse "failpoint1=retrun;failpoint2=print;failpoint3;=off" at offset 46: expected a failpoint name, found "="`,
+	}, "\n"), err.Error())
+	assert.ErrorIs(t, err, ErrBadParse)
+}
+
+func TestParseRawString(t *testing.T) {
+	tests := []struct {
+		desc string
+		want interface{}
+	}{
+		{"return(`abc`)", "abc"},
+		{"return(`a\\b\"c`)", `a\b"c`},
+		{"return(b`abc`)", []byte("abc")},
+		{"sleep(`10ms`)", "10ms"},
+		{"sleep(`10ms`..`20ms`)", Range{10 * time.Millisecond, 20 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.desc)
+		require.NoError(t, err, tt.desc)
+		assert.Equal(t, tt.want, got.Chain[0].Value, tt.desc)
+	}
+
+	err := Validate("return(`abc)")
+	require.Error(t, err)
+	var pe *ParseError
+	require.True(t, errors.As(err, &pe))
+	assert.Equal(t, "a valid quoted string", pe.Expected)
+}